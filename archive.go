@@ -0,0 +1,90 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrNoS3 is returned by ArchiveToS3 for a Client that has no S3 client: one created without
+// Config.S3 other than by NewClient.
+var ErrNoS3 = errors.New("sqs: archiving requires Config.S3 or a Client created with NewClient")
+
+// S3API is the part of the S3 client used to archive messages, satisfied by *s3.S3.
+type S3API interface {
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// archivedMessage is the JSON document written to S3 for each archived message.
+type archivedMessage struct {
+	ID         string               `json:"id"`
+	Body       string               `json:"body"`
+	SentTime   time.Time            `json:"sentTime,omitempty"`
+	Attributes map[string]Attribute `json:"attributes,omitempty"`
+}
+
+// ArchiveToS3 drains the queue into an S3 bucket and returns the number of messages archived. Each
+// message is written as a JSON object containing its ID, body, sent time and message attributes, at
+// the key prefix + message ID + ".json". A message is only deleted from the queue once its object has
+// been written, so if ArchiveToS3 fails, every message not yet archived is still in the queue,
+// although a message may be archived twice if deleting it fails. Draining stops once a receive
+// returns no messages or ctx is canceled.
+func (c *Client) ArchiveToS3(ctx context.Context, bucket, prefix string) (int, error) {
+	if c.s3 == nil {
+		return 0, ErrNoS3
+	}
+
+	archived := 0
+	for {
+		resp, err := c.receiveNitemsContext(ctx, MaxBatchSize)
+		if err != nil {
+			return archived, err
+		}
+
+		if len(resp.Messages) == 0 {
+			return archived, nil
+		}
+
+		for _, msg := range resp.Messages {
+			if err := c.archive(ctx, bucket, prefix, msg); err != nil {
+				return archived, err
+			}
+
+			if err := c.Delete(msg); err != nil {
+				return archived, err
+			}
+			archived++
+		}
+	}
+}
+
+// archive writes a message to S3.
+func (c *Client) archive(ctx context.Context, bucket, prefix string, msg *sqs.Message) error {
+	m := &Message{raw: msg}
+	doc := archivedMessage{
+		ID:         m.ID(),
+		Body:       m.Body(),
+		Attributes: m.Attributes(),
+	}
+	doc.SentTime, _ = m.SentTime()
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Body:        bytes.NewReader(b),
+		Bucket:      &bucket,
+		ContentType: aws.String("application/json"),
+		Key:         aws.String(prefix + m.ID() + ".json"),
+	})
+	return err
+}
@@ -0,0 +1,107 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// shortPollMock is a MockAPIService whose receives return straight away when the queue is empty
+// instead of long polling, for tests that drain the queue.
+type shortPollMock struct {
+	*MockAPIService
+}
+
+func (m shortPollMock) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	short := *input
+	short.WaitTimeSeconds = aws.Int64(0)
+	return m.MockAPIService.ReceiveMessageWithContext(ctx, &short, opts...)
+}
+
+// mockS3 is an S3API that keeps the objects written to it, or fails every write with err.
+type mockS3 struct {
+	err     error
+	objects map[string][]byte
+}
+
+func (m *mockS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	b, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.objects == nil {
+		m.objects = make(map[string][]byte)
+	}
+	m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)] = b
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestArchiveToS3(t *testing.T) {
+	errDenied := errors.New("access denied")
+	tests := []struct {
+		name      string
+		err       error // the error of every S3 write
+		archived  int
+		remaining int
+	}{
+		{name: "archived", archived: 2},
+		{name: "write fails", err: errDenied, remaining: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService("a", "b")
+			store := &mockS3{err: tt.err}
+			config := testConfig("orders")
+			config.S3 = store
+			c, err := newClient(config, shortPollMock{mock})
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			n, err := c.ArchiveToS3(context.Background(), "archive", "orders/")
+			if n != tt.archived || err != tt.err {
+				t.Fatalf("ArchiveToS3() = %d, %v, want %d, %v", n, err, tt.archived, tt.err)
+			}
+			if got := len(mock.Remaining()); got != tt.remaining {
+				t.Errorf("%d messages remain, want %d", got, tt.remaining)
+			}
+
+			var bodies []string
+			for key, b := range store.objects {
+				var doc archivedMessage
+				if err := json.Unmarshal(b, &doc); err != nil {
+					t.Fatalf("object %s: %v", key, err)
+				}
+				if want := "archive/orders/" + doc.ID + ".json"; key != want {
+					t.Errorf("object written to %s, want %s", key, want)
+				}
+				bodies = append(bodies, doc.Body)
+			}
+			sort.Strings(bodies)
+			if len(bodies) != tt.archived || tt.archived > 0 && (bodies[0] != "a" || bodies[1] != "b") {
+				t.Errorf("archived %q", bodies)
+			}
+		})
+	}
+}
+
+func TestArchiveToS3WithoutS3(t *testing.T) {
+	c := newTestClient(t, testConfig("orders"), NewMockAPIService("a"))
+	if _, err := c.ArchiveToS3(context.Background(), "archive", ""); err != ErrNoS3 {
+		t.Errorf("ArchiveToS3() = %v, want %v", err, ErrNoS3)
+	}
+}
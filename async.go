@@ -0,0 +1,144 @@
+package sqs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when inserting into a Client that has been closed.
+var ErrClosed = errors.New("sqs: client closed")
+
+// asyncInserter buffers messages passed to Insert and sends them in batches from a background
+// goroutine, which hands them to a BufferedProducer.
+type asyncInserter struct {
+	producer *BufferedProducer
+	delay    time.Duration
+	queue    chan string
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	errMu sync.Mutex
+	err   error
+}
+
+// newAsyncInserter starts an asyncInserter that buffers up to size messages.
+func newAsyncInserter(c *Client, size int) *asyncInserter {
+	count, bytes := c.batchLimits()
+	a := &asyncInserter{
+		producer: newBufferedProducer(c, c.config.MaxBatchDelay, count, bytes),
+		delay:    c.config.MaxBatchDelay,
+		queue:    make(chan string, size),
+		done:     make(chan struct{}),
+	}
+
+	go a.run()
+	return a
+}
+
+// insert buffers body, which must already have been checked with prepareMessage, blocking while
+// the buffer is full. If a background send has failed since the last call, its error is returned
+// instead and body is not buffered.
+func (a *asyncInserter) insert(body string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+
+	if err := a.takeErr(); err != nil {
+		return err
+	}
+
+	a.queue <- body
+	return nil
+}
+
+// close stops accepting messages, waits for the buffer to be sent and returns any error that has
+// not yet been returned.
+func (a *asyncInserter) close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	return a.takeErr()
+}
+
+// run passes buffered messages to the producer until the buffer is closed. The producer sends a
+// batch once it is full and, with a delay, every Config.MaxBatchDelay; without one, run flushes
+// whenever the buffer is empty.
+func (a *asyncInserter) run() {
+	defer close(a.done)
+
+	for body := range a.queue {
+		if err := a.producer.addChecked(body); err != nil {
+			a.setErr(err)
+		}
+
+		if a.delay <= 0 && len(a.queue) == 0 {
+			if err := a.producer.Flush(); err != nil {
+				a.setErr(err)
+			}
+		}
+	}
+
+	if err := a.producer.Close(); err != nil {
+		a.setErr(err)
+	}
+}
+
+func (a *asyncInserter) setErr(err error) {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+func (a *asyncInserter) takeErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Close sends any messages buffered by Insert when Config.InsertBufferSize is set. It returns the
+// first error from sending buffered messages that has not already been returned by Insert. If
+// Config.PublishCloudWatch is set, Close also publishes the metrics one last time and stops
+// publishing.
+func (c *Client) Close() error {
+	var err error
+	if c.async != nil {
+		err = c.async.close()
+	}
+
+	if c.cloudWatch != nil {
+		c.cloudWatch.close()
+	}
+
+	return err
+}
+
+// batchLimits returns the message count and byte limits of batches sent by the background batcher.
+func (c *Client) batchLimits() (count, bytes int) {
+	count, bytes = c.config.MaxBatchCount, c.config.MaxBatchBytes
+	if count <= 0 {
+		count = MaxBatchSize
+	}
+
+	if bytes <= 0 {
+		bytes = maxBatchBytes
+	}
+
+	return count, bytes
+}
@@ -0,0 +1,54 @@
+package sqs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestBufferedInsertOversizedBody(t *testing.T) {
+	mock := NewMockAPIService()
+	config := testConfig("orders")
+	config.InsertBufferSize = 10
+	c := newTestClient(t, config, mock)
+
+	if err := c.Insert(strings.Repeat("x", MaxMessageSize+1)); err != ErrMessageTooLarge {
+		t.Errorf("Insert() of an oversized body = %v, want %v", err, ErrMessageTooLarge)
+	}
+
+	for _, body := range []string{"a", "b"} {
+		if err := c.Insert(body); err != nil {
+			t.Errorf("Insert(%q) after the oversized body = %v", body, err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := mock.Sent(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("sent %q, want the valid bodies", got)
+	}
+}
+
+func TestBufferedInsertReportsDroppedMessages(t *testing.T) {
+	mock := NewMockAPIService()
+	config := testConfig("orders")
+	config.InsertBufferSize = 10
+	c := newTestClient(t, config, mock)
+
+	mock.FailNext("SendMessageBatch", awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), 403, "id"))
+	if err := c.Insert("a"); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	err := c.Close()
+	dropped, ok := err.(*DroppedError)
+	if !ok || !reflect.DeepEqual(dropped.Bodies, []string{"a"}) {
+		t.Errorf("Close() = %v, want a *DroppedError for the rejected message", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+}
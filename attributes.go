@@ -0,0 +1,328 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Mismatch describes a queue attribute whose value in AWS differs from the value in the Config.
+type Mismatch struct {
+	Attribute string
+	Expected  string
+	Actual    string
+}
+
+// MismatchError is returned by NewClient when Config.StrictCreate is set and the queue exists with
+// different attributes.
+type MismatchError struct {
+	Mismatches []Mismatch
+}
+
+func (e *MismatchError) Error() string {
+	diffs := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		diffs[i] = fmt.Sprintf("%s is %q, want %q", m.Attribute, m.Actual, m.Expected)
+	}
+	sort.Strings(diffs)
+
+	return "sqs: queue exists with different attributes: " + strings.Join(diffs, "; ")
+}
+
+// VerifyAttributes compares the attributes of the queue in AWS, including its default visibility
+// timeout, with those requested by the Config and returns any that differ. This is useful when
+// connecting to a queue that already existed, or that may have been reconfigured outside of this
+// package.
+func (c *Client) VerifyAttributes() ([]Mismatch, error) {
+	expected := c.queueAttributes()
+	if len(expected) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+
+	actual, err := c.getAttributes(names...)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	for name, value := range expected {
+		if !sameAttribute(name, *value, actual[name]) {
+			mismatches = append(mismatches, Mismatch{
+				Attribute: name,
+				Expected:  *value,
+				Actual:    actual[name],
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// sameAttribute reports whether the value of a queue attribute in AWS matches the expected value.
+// AWS reformats policies, so a Policy is compared as parsed JSON.
+func sameAttribute(name, expected, actual string) bool {
+	if name == sqs.QueueAttributeNamePolicy {
+		var e, a interface{}
+		if json.Unmarshal([]byte(expected), &e) == nil && json.Unmarshal([]byte(actual), &a) == nil {
+			return reflect.DeepEqual(e, a)
+		}
+	}
+
+	return expected == actual
+}
+
+// redrivePolicy is the JSON document stored in the RedrivePolicy queue attribute. AWS has returned
+// maxReceiveCount both as a number and as a string.
+type redrivePolicy struct {
+	DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+	MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+}
+
+// DeadLetterConfig returns the ARN of the dead letter queue that messages are moved to after being
+// received maxReceiveCount times without being deleted. If the queue has no dead letter queue the
+// ARN is empty.
+func (c *Client) DeadLetterConfig() (arn string, maxReceiveCount int, err error) {
+	attrs, err := c.getAttributes(sqs.QueueAttributeNameRedrivePolicy)
+	if err != nil {
+		return "", 0, err
+	}
+
+	raw := attrs[sqs.QueueAttributeNameRedrivePolicy]
+	if raw == "" {
+		return "", 0, nil
+	}
+
+	var policy redrivePolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return "", 0, fmt.Errorf("sqs: invalid RedrivePolicy: %v", err)
+	}
+
+	count, err := policy.MaxReceiveCount.Int64()
+	if err != nil {
+		return "", 0, fmt.Errorf("sqs: invalid RedrivePolicy maxReceiveCount: %v", err)
+	}
+
+	return policy.DeadLetterTargetArn, int(count), nil
+}
+
+// Stats holds approximate message counts for a queue. The counts can lag the actual state of the
+// queue by up to a minute.
+type Stats struct {
+	// Visible is the number of messages available to be received.
+	Visible int
+	// InFlight is the number of messages that have been received but not yet deleted.
+	InFlight int
+	// Delayed is the number of messages that are not yet available because of a delivery delay.
+	Delayed int
+}
+
+// Stats returns approximate message counts for the queue. Like ApproximateLen, it returns an error
+// if AWS does not report one of the counts.
+func (c *Client) Stats() (Stats, error) {
+	attrs, err := c.getAttributes(
+		sqs.QueueAttributeNameApproximateNumberOfMessages,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+	)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	counts := []struct {
+		name string
+		dst  *int
+	}{
+		{sqs.QueueAttributeNameApproximateNumberOfMessages, &stats.Visible},
+		{sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible, &stats.InFlight},
+		{sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed, &stats.Delayed},
+	}
+	for _, count := range counts {
+		v, ok := attrs[count.name]
+		if !ok {
+			return Stats{}, fmt.Errorf("sqs: queue has no %s attribute", count.name)
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Stats{}, fmt.Errorf("sqs: invalid %s %q: %v", count.name, v, err)
+		}
+		*count.dst = n
+	}
+
+	return stats, nil
+}
+
+// VisibilityTimeout returns the visibility timeout of the queue in seconds as set in AWS. It can
+// differ from Config.VisibilityTimeoutSeconds, for example if the queue already existed with a
+// different setting, so use it to decide how often to extend the visibility of messages being
+// processed.
+func (c *Client) VisibilityTimeout() (int, error) {
+	return c.intAttribute(sqs.QueueAttributeNameVisibilityTimeout)
+}
+
+// intAttribute returns the value of a numeric queue attribute. It returns an error if AWS does not
+// return the attribute, for example when the caller lacks permission to read it.
+func (c *Client) intAttribute(name string) (int, error) {
+	attrs, err := c.getAttributes(name)
+	if err != nil {
+		return 0, err
+	}
+
+	v, ok := attrs[name]
+	if !ok {
+		return 0, fmt.Errorf("sqs: queue has no %s attribute", name)
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("sqs: invalid %s %q: %v", name, v, err)
+	}
+
+	return n, nil
+}
+
+// RecommendedWorkers suggests how many workers should process the queue so that each has a backlog
+// of at most targetPerWorker messages, counting both visible and in-flight messages. It returns 0
+// for an empty queue. This is only a heuristic for autoscaling: it is based on the approximate
+// counts from Stats, which lag the queue, and takes no account of how long messages take to
+// process.
+func (c *Client) RecommendedWorkers(targetPerWorker int) (int, error) {
+	if targetPerWorker < 1 {
+		return 0, errors.New("sqs: targetPerWorker must be at least 1")
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		return 0, err
+	}
+
+	backlog := stats.Visible + stats.InFlight
+	return (backlog + targetPerWorker - 1) / targetPerWorker, nil
+}
+
+// IsEmpty reports whether the queue currently has no visible messages. Unlike the counts from Stats
+// it does not lag, because it tries to receive a message without waiting and with a visibility
+// timeout of 0, so any message found stays visible to other consumers. A short poll only samples a
+// subset of the SQS servers, so on a queue with very few messages it can occasionally report empty
+// when it is not; check more than once when that matters.
+//
+// The message found is really received, so it counts towards its receive count and, if the queue
+// has a redrive policy, towards moving it to the dead letter queue; calling IsEmpty in a tight loop
+// can push messages there. It is not counted as received by this Client, is not retained for
+// Replay and its receipt handle is not tracked.
+func (c *Client) IsEmpty(ctx context.Context) (bool, error) {
+	input := c.receiveInput(1)
+	input.VisibilityTimeout = aws.Int64(0)
+	input.WaitTimeSeconds = aws.Int64(0)
+
+	resp, err := c.fetch(ctx, input)
+	if err != nil {
+		return false, err
+	}
+
+	return len(resp.Messages) == 0, nil
+}
+
+// maxEmptyPollInterval is the longest WaitUntilEmpty waits between checks.
+const maxEmptyPollInterval = 5 * time.Minute
+
+// WaitUntilEmpty blocks until the queue has no visible or in-flight messages, or until ctx is
+// canceled. To limit the number of requests during a long drain, the time between checks adapts to
+// the rate the queue is draining at: it is half the time the remaining messages are expected to take,
+// doubling while the queue is not shrinking, and is kept between pollInterval and 5 minutes. So the
+// queue is checked rarely while the backlog is large and every pollInterval, which must be greater
+// than 0, as it nears empty. Because the counts are approximate, the queue should be considered
+// empty only once no more messages are being inserted.
+func (c *Client) WaitUntilEmpty(ctx context.Context, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return fmt.Errorf("sqs: poll interval %v must be greater than 0", pollInterval)
+	}
+
+	interval := pollInterval
+	var last int
+	var lastAt time.Time
+	for {
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+
+		backlog := stats.Visible + stats.InFlight
+		if backlog == 0 {
+			return nil
+		}
+
+		now := c.now()
+		if !lastAt.IsZero() {
+			interval = nextEmptyPoll(interval, pollInterval, last-backlog, backlog, now.Sub(lastAt))
+		}
+		last, lastAt = backlog, now
+
+		if !sleep(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// nextEmptyPoll returns how long WaitUntilEmpty waits before its next check, given that drained
+// messages were removed from the queue in elapsed, leaving backlog.
+func nextEmptyPoll(interval, min time.Duration, drained, backlog int, elapsed time.Duration) time.Duration {
+	if drained > 0 {
+		interval = time.Duration(float64(elapsed) * float64(backlog) / float64(drained) / 2)
+	} else {
+		interval *= 2
+	}
+
+	max := maxEmptyPollInterval
+	if max < min {
+		max = min
+	}
+
+	switch {
+	case interval < min:
+		return min
+	case interval > max:
+		return max
+	}
+
+	return interval
+}
+
+// getAttributes returns the named attributes of the queue.
+func (c *Client) getAttributes(names ...string) (map[string]string, error) {
+	request := &sqs.GetQueueAttributesInput{
+		QueueUrl:       c.currentURL(),
+		AttributeNames: aws.StringSlice(names),
+	}
+
+	var response *sqs.GetQueueAttributesOutput
+	err := c.withURL(&request.QueueUrl, func() error {
+		var err error
+		response, err = c.client.GetQueueAttributes(request)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response == nil {
+		return map[string]string{}, nil
+	}
+
+	return aws.StringValueMap(response.Attributes), nil
+}
@@ -0,0 +1,73 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// attributeMock is a MockAPIService whose GetQueueAttributes responses have their attributes
+// replaced by attrs, to simulate responses the mock would not give.
+type attributeMock struct {
+	*MockAPIService
+	attrs map[string]string
+}
+
+func (m attributeMock) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	if _, err := m.MockAPIService.GetQueueAttributes(input); err != nil {
+		return nil, err
+	}
+
+	return &sqs.GetQueueAttributesOutput{Attributes: aws.StringMap(m.attrs)}, nil
+}
+
+func TestApproximateLen(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  int
+		valid bool
+	}{
+		{name: "count", attrs: map[string]string{sqs.QueueAttributeNameApproximateNumberOfMessages: "3"}, want: 3, valid: true},
+		{name: "missing", attrs: map[string]string{}},
+		{name: "invalid", attrs: map[string]string{sqs.QueueAttributeNameApproximateNumberOfMessages: "many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newClient(testConfig("orders"), attributeMock{NewMockAPIService(), tt.attrs})
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			got, err := c.ApproximateLen()
+			if (err == nil) != tt.valid || got != tt.want {
+				t.Errorf("ApproximateLen() = %d, %v, want %d and valid %v", got, err, tt.want, tt.valid)
+			}
+		})
+	}
+}
+
+func TestStatsMissingCount(t *testing.T) {
+	attrs := map[string]string{
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           "3",
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: "1",
+	}
+	c, err := newClient(testConfig("orders"), attributeMock{NewMockAPIService(), attrs})
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+
+	if stats, err := c.Stats(); err == nil {
+		t.Errorf("Stats() = %+v, want an error for the missing delayed count", stats)
+	}
+}
+
+func TestApproximateLenMock(t *testing.T) {
+	c := newTestClient(t, testConfig("orders"), NewMockAPIService("a", "b"))
+
+	if n, err := c.ApproximateLen(); err != nil || n != 2 {
+		t.Errorf("ApproximateLen() = %d, %v, want 2", n, err)
+	}
+}
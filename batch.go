@@ -0,0 +1,218 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MaxBatchSize is the largest number of messages SQS accepts in a single batch request.
+const MaxBatchSize = 10
+
+// deleteEntries holds the entries of a delete batch request. Consumers delete a batch for every
+// batch they receive, so the entries are pooled rather than allocated for each request.
+type deleteEntries struct {
+	values [MaxBatchSize]sqs.DeleteMessageBatchRequestEntry
+	ptrs   [MaxBatchSize]*sqs.DeleteMessageBatchRequestEntry
+}
+
+// deleteEntryIDs are the IDs of the entries of a delete batch request, which are their indexes.
+var deleteEntryIDs = [MaxBatchSize]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+var deleteEntriesPool = sync.Pool{
+	New: func() interface{} { return new(deleteEntries) },
+}
+
+// release clears the entries, so they do not keep receipt handles alive, and returns them to the
+// pool.
+func (e *deleteEntries) release() {
+	*e = deleteEntries{}
+	deleteEntriesPool.Put(e)
+}
+
+// ErrBatchTooLarge is returned when a batch request has more than MaxBatchSize messages.
+var ErrBatchTooLarge = errors.New("sqs: batch requests can have at most 10 messages")
+
+// BatchResult is the result of inserting a single message as part of a batch.
+type BatchResult struct {
+	// MessageID is the ID AWS assigned to the message. It is empty if the insert failed.
+	MessageID string
+	// Err is non-nil if the message was not inserted.
+	Err error
+}
+
+// BatchEntryError describes why AWS rejected a single entry of a batch request.
+type BatchEntryError struct {
+	Code    string
+	Message string
+	// SenderFault is true if the entry was rejected because of a problem with the request, rather
+	// than a problem on the AWS side that may succeed if retried.
+	SenderFault bool
+}
+
+func (e *BatchEntryError) Error() string {
+	return fmt.Sprintf("sqs: batch entry failed: %s: %s", e.Code, e.Message)
+}
+
+// BatchError is returned when some entries of a batch request fail while others succeed.
+type BatchError struct {
+	// Failed maps the ID of each message that failed to the reason it failed. For InsertBatch, which
+	// has no message IDs for the strings it failed to insert, it maps the index of each failed string
+	// in the inputs, such as "0", instead.
+	Failed map[string]*BatchEntryError
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("sqs: %d batch entries failed", len(e.Failed))
+}
+
+// newBatchError returns a *BatchError for the failed entries of a batch response, or nil if there
+// are none.
+func newBatchError(failed []*sqs.BatchResultErrorEntry) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	e := &BatchError{Failed: make(map[string]*BatchEntryError, len(failed))}
+	for _, f := range failed {
+		e.Failed[aws.StringValue(f.Id)] = &BatchEntryError{
+			Code:        aws.StringValue(f.Code),
+			Message:     aws.StringValue(f.Message),
+			SenderFault: aws.BoolValue(f.SenderFault),
+		}
+	}
+
+	return e
+}
+
+// missingResultCode is the code of the *BatchEntryError for an entry that a SendMessageBatch
+// response lists as neither successful nor failed.
+const missingResultCode = "MissingResult"
+
+// batchResults correlates a SendMessageBatch response, which lists entries in no particular order,
+// with the request entries and returns a result for each entry in request order. An entry missing
+// from the response, or every entry if there is no response, fails with a retryable
+// *BatchEntryError, since it may not have been sent.
+func batchResults(entries []*sqs.SendMessageBatchRequestEntry, resp *sqs.SendMessageBatchOutput) []BatchResult {
+	index := make(map[string]int, len(entries))
+	for i, e := range entries {
+		index[aws.StringValue(e.Id)] = i
+	}
+
+	results := make([]BatchResult, len(entries))
+	for i := range results {
+		results[i].Err = &BatchEntryError{
+			Code:    missingResultCode,
+			Message: "the batch response has no result for the entry",
+		}
+	}
+	if resp == nil {
+		return results
+	}
+
+	for _, s := range resp.Successful {
+		if i, ok := index[aws.StringValue(s.Id)]; ok {
+			results[i].MessageID = aws.StringValue(s.MessageId)
+			results[i].Err = nil
+		}
+	}
+
+	for _, f := range resp.Failed {
+		if i, ok := index[aws.StringValue(f.Id)]; ok {
+			results[i].Err = &BatchEntryError{
+				Code:        aws.StringValue(f.Code),
+				Message:     aws.StringValue(f.Message),
+				SenderFault: aws.BoolValue(f.SenderFault),
+			}
+		}
+	}
+
+	return results
+}
+
+// defaultBatchRetryBackoff is the wait before the first retry of a batch when
+// Config.BatchRetryBackoff is not set.
+const defaultBatchRetryBackoff = 100 * time.Millisecond
+
+// newInsertBatchError returns a *BatchError for the failed results of InsertBatchResults, keyed by
+// the index of each failed string in the inputs, or nil if there are none.
+func newInsertBatchError(results []BatchResult) error {
+	var e *BatchError
+	for i, r := range results {
+		entryErr, ok := r.Err.(*BatchEntryError)
+		if !ok {
+			continue
+		}
+
+		if e == nil {
+			e = &BatchError{Failed: make(map[string]*BatchEntryError)}
+		}
+		e.Failed[strconv.Itoa(i)] = entryErr
+	}
+
+	if e == nil {
+		return nil
+	}
+
+	return e
+}
+
+// isRetryableEntry reports whether a batch entry that failed with err may succeed if sent again.
+func isRetryableEntry(err error) bool {
+	var entryErr *BatchEntryError
+	return errors.As(err, &entryErr) && !entryErr.SenderFault
+}
+
+// sendBatchWithRetries is like sendBatch but resends the entries that failed with a retryable
+// error, up to Config.BatchRetries times with exponential backoff. The results of resent entries
+// replace their earlier results, so they stay in request order. A request that fails as a whole
+// during a retry, or ctx being done, ends the retries and leaves the earlier results of the
+// entries.
+func (c *Client) sendBatchWithRetries(ctx context.Context, entries []*sqs.SendMessageBatchRequestEntry) ([]BatchResult, error) {
+	results, err := c.sendBatch(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := c.config.BatchRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultBatchRetryBackoff
+	}
+
+	for attempt := 0; attempt < c.config.BatchRetries; attempt++ {
+		var retry []*sqs.SendMessageBatchRequestEntry
+		var index []int
+		for i, r := range results {
+			if isRetryableEntry(r.Err) {
+				retry = append(retry, entries[i])
+				index = append(index, i)
+			}
+		}
+
+		if len(retry) == 0 {
+			break
+		}
+
+		if !sleep(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+
+		retried, err := c.sendBatch(ctx, retry)
+		if err != nil {
+			break
+		}
+
+		for j, r := range retried {
+			results[index[j]] = r
+		}
+	}
+
+	return results, nil
+}
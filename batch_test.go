@@ -0,0 +1,243 @@
+package sqs
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// reversingMock is a MockAPIService whose SendMessageBatch responses list their entries in the
+// reverse of the request order, as AWS is free to.
+type reversingMock struct {
+	*MockAPIService
+}
+
+func (m reversingMock) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	out, err := m.MockAPIService.SendMessageBatchWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(out.Successful)-1; i < j; i, j = i+1, j-1 {
+		out.Successful[i], out.Successful[j] = out.Successful[j], out.Successful[i]
+	}
+	for i, j := 0, len(out.Failed)-1; i < j; i, j = i+1, j-1 {
+		out.Failed[i], out.Failed[j] = out.Failed[j], out.Failed[i]
+	}
+
+	return out, nil
+}
+
+func TestInsertBatchResultsOrder(t *testing.T) {
+	inputs := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name   string
+		failed int
+	}{
+		{name: "all inserted"},
+		{name: "first two failed", failed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			mock.FailBatchEntries(tt.failed)
+			c, err := newClient(testConfig("orders"), reversingMock{mock})
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			results, err := c.InsertBatchResults(inputs)
+			if err != nil {
+				t.Fatalf("InsertBatchResults: %v", err)
+			}
+			if len(results) != len(inputs) {
+				t.Fatalf("InsertBatchResults returned %d results, want %d", len(results), len(inputs))
+			}
+
+			msgs, err := c.PeekBatch()
+			if err != nil {
+				t.Fatalf("PeekBatch: %v", err)
+			}
+			bodies := make(map[string]string, len(msgs))
+			for _, msg := range msgs {
+				bodies[aws.StringValue(msg.MessageId)] = aws.StringValue(msg.Body)
+			}
+
+			for i, r := range results {
+				if i < tt.failed {
+					if r.Err == nil {
+						t.Errorf("results[%d] inserted, want the failure for %q", i, inputs[i])
+					}
+					continue
+				}
+
+				if r.Err != nil {
+					t.Errorf("results[%d].Err = %v", i, r.Err)
+				}
+				if body := bodies[r.MessageID]; body != inputs[i] {
+					t.Errorf("results[%d] is the message %q, want %q", i, body, inputs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInsertBatchRetries(t *testing.T) {
+	inputs := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name    string
+		retries int
+		fail    int      // the number of entries the mock rejects as throttled
+		failed  []string // the keys of the *BatchError, if one is expected
+	}{
+		{name: "all inserted", retries: 1},
+		{name: "retried", retries: 1, fail: 2},
+		{name: "no retries", fail: 2, failed: []string{"0", "1"}},
+		{name: "retries exhausted", retries: 1, fail: 6, failed: []string{"0", "1"}},
+		{name: "second retry", retries: 2, fail: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			mock.FailBatchEntries(tt.fail)
+			config := testConfig("orders")
+			config.BatchRetries = tt.retries
+			config.BatchRetryBackoff = time.Millisecond
+			c := newTestClient(t, config, mock)
+
+			err := c.InsertBatch(inputs)
+			if tt.failed == nil {
+				if err != nil {
+					t.Fatalf("InsertBatch() = %v", err)
+				}
+			} else {
+				var batchErr *BatchError
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("InsertBatch() = %v, want a *BatchError", err)
+				}
+
+				var keys []string
+				for k, e := range batchErr.Failed {
+					keys = append(keys, k)
+					if e.Code != "ThrottlingException" || e.SenderFault {
+						t.Errorf("Failed[%s] = %+v, want the throttling error", k, e)
+					}
+				}
+				sort.Strings(keys)
+				if !reflect.DeepEqual(keys, tt.failed) {
+					t.Errorf("failed entries = %q, want %q", keys, tt.failed)
+				}
+			}
+
+			if sent := len(mock.Sent()); sent != len(inputs)-len(tt.failed) {
+				t.Errorf("%d messages sent, want %d", sent, len(inputs)-len(tt.failed))
+			}
+		})
+	}
+}
+
+func TestBatchResultsUnaccountedEntries(t *testing.T) {
+	entries := []*sqs.SendMessageBatchRequestEntry{{Id: aws.String("0")}, {Id: aws.String("1")}}
+	tests := []struct {
+		name string
+		resp *sqs.SendMessageBatchOutput
+		sent []bool
+	}{
+		{name: "no response", sent: []bool{false, false}},
+		{
+			name: "entry missing",
+			resp: &sqs.SendMessageBatchOutput{
+				Successful: []*sqs.SendMessageBatchResultEntry{{Id: aws.String("1"), MessageId: aws.String("m1")}},
+			},
+			sent: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, r := range batchResults(entries, tt.resp) {
+				if sent := r.Err == nil; sent != tt.sent[i] {
+					t.Errorf("results[%d] = %+v, want sent %v", i, r, tt.sent[i])
+				}
+				if r.Err != nil && !isRetryableEntry(r.Err) {
+					t.Errorf("results[%d].Err = %v, want a retryable entry error", i, r.Err)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkMessages returns n received messages with IDs and receipt handles of realistic length.
+func benchmarkMessages(n int) []*sqs.Message {
+	msgs := make([]*sqs.Message, n)
+	for i := range msgs {
+		msgs[i] = &sqs.Message{
+			MessageId:     aws.String(strings.Repeat("0", 32) + strconv.Itoa(i)),
+			ReceiptHandle: aws.String(strings.Repeat("h", 400) + strconv.Itoa(i)),
+		}
+	}
+
+	return msgs
+}
+
+func BenchmarkDeleteBatchEntries(b *testing.B) {
+	msgs := benchmarkMessages(MaxBatchSize)
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			makeDeleteMsgBatchRequestEntry(msgs).release()
+		}
+	})
+
+	// allocated builds the entries as they were built before they were pooled, for comparison.
+	b.Run("allocated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var entries []*sqs.DeleteMessageBatchRequestEntry
+			for _, msg := range msgs {
+				entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+					ReceiptHandle: msg.ReceiptHandle,
+					Id:            msg.MessageId,
+				})
+			}
+			_ = entries
+		}
+	})
+}
+
+func BenchmarkPopBatch(b *testing.B) {
+	mock := NewMockAPIService()
+	c, err := NewMockClient(testConfig("orders"), mock)
+	if err != nil {
+		b.Fatalf("NewMockClient: %v", err)
+	}
+
+	bodies := make([]string, MaxBatchSize)
+	for i := range bodies {
+		bodies[i] = "message " + strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := c.InsertBatch(bodies); err != nil {
+			b.Fatalf("InsertBatch: %v", err)
+		}
+		b.StartTimer()
+
+		if _, err := c.PopBatch(); err != nil {
+			b.Fatalf("PopBatch: %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ErrBreakerOpen is returned without calling AWS while the circuit breaker is open.
+var ErrBreakerOpen = errors.New("sqs: circuit breaker open")
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed allows all calls.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all calls until the cooldown has passed.
+	BreakerOpen
+	// BreakerHalfOpen allows a single call to test whether AWS has recovered.
+	BreakerHalfOpen
+)
+
+// breakerStateMetric is the gauge the breaker state is reported to.
+const breakerStateMetric = "breaker_state"
+
+// breaker is a circuit breaker that opens after a number of consecutive failures.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	metrics   Metrics
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns ErrBreakerOpen if a call should not be made.
+func (b *breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.setState(BreakerHalfOpen)
+	}
+
+	switch {
+	case b.state == BreakerOpen:
+		return ErrBreakerOpen
+	case b.state == BreakerHalfOpen && b.probing:
+		return ErrBreakerOpen
+	case b.state == BreakerHalfOpen:
+		b.probing = true
+	}
+
+	return nil
+}
+
+// record updates the breaker with the result of a call allowed by allow. Only errors for which
+// isOutage is true count as failures: any other response shows AWS is available.
+func (b *breaker) record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !isOutage(err) {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// release gives up a call allowed by allow without recording a result, for example because it was
+// canceled.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// setState changes the state and reports it. b.mu must be held.
+func (b *breaker) setState(s BreakerState) {
+	if b.state != s {
+		b.state = s
+		b.metrics.Gauge(breakerStateMetric, float64(s))
+	}
+}
+
+// isOutage reports whether err suggests AWS is unavailable rather than that the request was at
+// fault: throttling, a 5xx response or a failure to reach AWS at all. Errors such as an expired
+// receipt handle or a missing queue are the caller's problem and do not open the breaker.
+func isOutage(err error) bool {
+	if err == nil || request.IsErrorExpiredCreds(err) {
+		return false
+	}
+
+	if request.IsErrorThrottle(err) || request.IsErrorRetryable(err) {
+		return true
+	}
+
+	var failure awserr.RequestFailure
+	return errors.As(err, &failure) && failure.StatusCode() >= 500
+}
+
+// guard calls fn if the circuit breaker allows it and records the result. A call that fails because
+// ctx is done is not recorded, as in fetch.
+func (c *Client) guard(ctx context.Context, fn func() error) error {
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	if ctx.Err() != nil {
+		c.breaker.release()
+	} else {
+		c.breaker.record(err)
+	}
+
+	return err
+}
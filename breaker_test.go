@@ -0,0 +1,103 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestIsOutage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil"},
+		{name: "throttled", err: awserr.New("ThrottlingException", "Rate exceeded", nil), want: true},
+		{
+			name: "server error",
+			err:  awserr.NewRequestFailure(awserr.New("InternalError", "internal error", nil), 500, "id"),
+			want: true,
+		},
+		{name: "transport", err: awserr.New("RequestError", "send request failed", errors.New("connection refused")), want: true},
+		{name: "expired handle", err: awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, "receipt handle is invalid", nil)},
+		{
+			name: "client error",
+			err:  awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), 403, "id"),
+		},
+		{name: "missing queue", err: errQueueNotExist},
+		{name: "expired credentials", err: awserr.New("ExpiredToken", "token expired", nil)},
+		{name: "other", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		if got := isOutage(tt.err); got != tt.want {
+			t.Errorf("isOutage(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBreakerCountsOnlyOutages(t *testing.T) {
+	staleID := "stale"
+	tests := []struct {
+		name string
+		call func(c *Client, mock *MockAPIService) error
+		open bool
+	}{
+		{
+			name: "stale receipt handles",
+			call: func(c *Client, mock *MockAPIService) error {
+				return c.Delete(&sqs.Message{MessageId: &staleID, ReceiptHandle: &staleID})
+			},
+		},
+		{
+			name: "canceled requests",
+			call: func(c *Client, mock *MockAPIService) error {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return c.InsertContext(ctx, "body")
+			},
+		},
+		{
+			name: "throttled",
+			call: func(c *Client, mock *MockAPIService) error {
+				mock.FailNext("SendMessage", awserr.New("ThrottlingException", "Rate exceeded", nil))
+				return c.Insert("body")
+			},
+			open: true,
+		},
+		{
+			name: "throttled deletes",
+			call: func(c *Client, mock *MockAPIService) error {
+				mock.FailNext("DeleteMessageBatch", awserr.New("ThrottlingException", "Rate exceeded", nil))
+				return c.DeleteBatch([]*sqs.Message{{MessageId: &staleID, ReceiptHandle: &staleID}})
+			},
+			open: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			config := testConfig("orders")
+			config.BreakerThreshold = 3
+			config.BreakerCooldown = time.Hour
+			c := newTestClient(t, config, mock)
+
+			for i := 0; i < config.BreakerThreshold; i++ {
+				if err := tt.call(c, mock); err == nil {
+					t.Fatalf("call %d succeeded, want it to fail", i)
+				}
+			}
+
+			err := c.Insert("body")
+			if (err == ErrBreakerOpen) != tt.open {
+				t.Errorf("Insert() after %d failures = %v, want breaker open %v", config.BreakerThreshold, err, tt.open)
+			}
+		})
+	}
+}
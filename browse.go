@@ -0,0 +1,82 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// browseVisibilityTimeoutSeconds is how long messages are hidden from consumers while browsing.
+const browseVisibilityTimeoutSeconds = 2
+
+// Browser pages through the messages in the queue without deleting them. See Browse.
+type Browser struct {
+	client   *Client
+	ctx      context.Context
+	pageSize int
+	// seen holds the IDs of the messages returned so far. Messages are tracked by ID rather than
+	// receipt handle because every receive of a message returns a new receipt handle.
+	seen map[string]bool
+}
+
+// Browse returns a Browser that reads the queue in pages of up to pageSize messages, for
+// administrative tools that need to inspect a queue. ctx bounds every page read by the Browser.
+//
+// Each message is hidden from consumers for only a couple of seconds after it is read, so browsing
+// barely disturbs them, and a message is returned at most once per Browser even if it is received
+// again, since the Browser remembers the IDs of the messages it has returned. Browsing a live queue
+// has inherent limits: SQS samples a subset of its servers on each receive, so some messages may
+// not be returned; messages in flight with consumers are not seen; and messages already returned
+// may be deleted or new ones added while browsing. Every read also counts as a receive, which
+// increases the receive count used by dead letter queues.
+func (c *Client) Browse(ctx context.Context, pageSize int) *Browser {
+	if pageSize < 1 {
+		pageSize = MaxBatchSize
+	}
+
+	return &Browser{
+		client:   c,
+		ctx:      ctx,
+		pageSize: pageSize,
+		seen:     make(map[string]bool),
+	}
+}
+
+// Next returns the next page of messages that have not been returned by this Browser before. An
+// empty page means no unseen messages were found.
+func (b *Browser) Next() ([]*sqs.Message, error) {
+	var page []*sqs.Message
+	for len(page) < b.pageSize {
+		n := b.pageSize - len(page)
+		if n > MaxBatchSize {
+			n = MaxBatchSize
+		}
+
+		input := b.client.receiveInput(n)
+		input.VisibilityTimeout = aws.Int64(browseVisibilityTimeoutSeconds)
+		input.WaitTimeSeconds = aws.Int64(0)
+		resp, err := b.client.receive(b.ctx, input)
+		if err != nil {
+			return page, err
+		}
+
+		fresh := 0
+		for _, msg := range resp.Messages {
+			id := aws.StringValue(msg.MessageId)
+			if b.seen[id] {
+				continue
+			}
+
+			b.seen[id] = true
+			page = append(page, msg)
+			fresh++
+		}
+
+		if fresh == 0 {
+			break
+		}
+	}
+
+	return page, nil
+}
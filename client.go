@@ -2,14 +2,38 @@
 package sqs
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
+// ErrMissingGroupID is returned when inserting into a FIFO queue without a message group ID.
+var ErrMissingGroupID = errors.New("sqs: FIFO queues require a message group ID, use InsertWithGroup")
+
+// ErrReceiptHandleExpired is returned, wrapped, when deleting a message whose visibility timeout
+// had already expired, so that its receipt handle was no longer valid. The message is still in the
+// queue and may already have been received again, by this or another consumer, so it will be
+// processed again; this is expected under at-least-once delivery rather than a failure to handle.
+var ErrReceiptHandleExpired = errors.New("sqs: receipt handle has expired")
+
+// ErrEmptyReceiptHandle is returned when deleting a message that has no receipt handle, for example
+// because it was lost or truncated while passing through another system.
+var ErrEmptyReceiptHandle = errors.New("sqs: message has no receipt handle")
+
 // Config contains required parameters to create a Client.
 type Config struct {
 	// AWS Region the queue is in. Ex. 'us-west-1'. For a list of regions visit:
@@ -19,240 +43,1304 @@ type Config struct {
 	Name string
 	// The amount of time after receiving an item before it can be pulled from the queue again.
 	// This should be enough time to process and delete the message. This must be greater than 0.
+	// It is used on every receive, and is also the default visibility timeout of the queue when
+	// NewClient creates it, which VerifyAttributes checks.
 	VisibilityTimeoutSeconds int
+	// Compress gzips message bodies on insert when doing so makes them smaller. Compressed messages
+	// are marked with a message attribute and transparently decompressed when received. A received
+	// message that cannot be decompressed is left out of the messages returned, counted in the
+	// "messages_corrupt" metric and moved to QuarantineQueue if it is set.
+	Compress bool
+	// BodyValidator, if set, is called with the body of each message delivered by Peek, Pop, their
+	// batch variants, Receive, ReceiveJSON, Consume and Process. Messages that fail validation are
+	// not returned to the caller; batch receives return the valid messages along with the error for
+	// the first invalid one.
+	BodyValidator func(body string) error
+	// Name of the queue that messages failing BodyValidator are moved to. If empty, invalid messages
+	// are left in the queue and the validation error is returned. Messages are moved with the body
+	// and attributes they were received with; a FIFO quarantine queue needs content-based
+	// deduplication.
+	QuarantineQueue string
+	// DeduplicationScope and FifoThroughputLimit enable high throughput mode on FIFO queues when set
+	// to "messageGroup" and "perMessageGroupId" respectively. DeduplicationScope may also be "queue"
+	// and FifoThroughputLimit "perQueue". Both are left at the AWS defaults when empty.
+	DeduplicationScope  string
+	FifoThroughputLimit string
+	// The number of seconds SQS retains a message, between 60 (1 minute) and 1209600 (14 days). The
+	// AWS default of 4 days is used when 0.
+	MessageRetentionSeconds int
+	// ID of the KMS key used to encrypt messages. Server side encryption is disabled when empty.
+	KMSMasterKeyID string
+	// ProducerName, if set, is attached to every inserted message as the "producer" message
+	// attribute, for example a service name or the result of os.Hostname. It can be read from
+	// received messages with Producer.
+	ProducerName string
+	// SchemaVersion, if set, is attached to every inserted message as the "schema-version" message
+	// attribute, identifying the version of the message format. Consumers can read it with
+	// SchemaVersion, or reject versions they do not support with
+	// ConsumeOptions.SupportedSchemaVersions.
+	SchemaVersion string
+	// DefaultAttributes are attached to every inserted message, for example to record the
+	// environment or service that sent it. Attributes passed with the message replace default
+	// attributes of the same name, as do the attributes set by ProducerName and SchemaVersion. A
+	// message with more than 10 attributes after the defaults are added is rejected with
+	// ErrTooManyAttributes.
+	DefaultAttributes map[string]Attribute
+	// Metrics, if set, receives measurements such as the circuit breaker state, the number of
+	// messages sent, received and deleted, and the duration of each send, receive and delete request.
+	// Failed requests are also counted, in counters named after the request with an "_errors"
+	// suffix, such as "receive_errors".
+	Metrics Metrics
+	// BreakerThreshold is the number of consecutive failed calls to AWS after which the circuit
+	// breaker opens and calls fail with ErrBreakerOpen. Only throttling, 5xx responses and failures
+	// to reach AWS count; errors caused by the request, such as an expired receipt handle, do not.
+	// After BreakerCooldown a single call is allowed through; if it succeeds the breaker closes,
+	// otherwise it opens again. The breaker is disabled when BreakerThreshold is 0.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Profile is the name of a profile in the shared AWS config and credentials files to use. When
+	// empty, the default credential chain is used.
+	Profile string
+	// Policy is the access policy of the queue as a JSON document, for example to allow an SNS topic
+	// to send messages to it. No policy is set when empty.
+	Policy string
+	// BatchRetries is the number of times InsertBatch and InsertBatchResults resend the entries of a
+	// batch that AWS rejected for a reason that may succeed if retried, such as throttling. Entries
+	// rejected because of a problem with the message itself are not retried. Defaults to 0, which
+	// does not retry.
+	BatchRetries int
+	// BatchRetryBackoff is how long to wait before the first retry of a batch; the wait doubles for
+	// each retry after that. Defaults to 100 milliseconds.
+	BatchRetryBackoff time.Duration
+	// InsertBufferSize, if greater than 0, makes Insert add messages to an in-memory buffer of this
+	// size that is sent to the queue in batches by a background goroutine, through a
+	// BufferedProducer, so messages that fail because of throttling or an outage are retried with
+	// the next batch and messages AWS rejects are dropped and reported in a *DroppedError. Insert
+	// checks each message before buffering it and blocks while the buffer is full. Close must be
+	// called to send the remaining messages.
+	InsertBufferSize int
+	// MaxBatchCount is the most messages the background batcher used with InsertBufferSize sends in
+	// one batch, between 1 and 10. Defaults to 10.
+	MaxBatchCount int
+	// MaxBatchBytes is the largest combined body size of a batch sent by the background batcher, up
+	// to the SQS limit of 256 KB, which is the default.
+	MaxBatchBytes int
+	// MaxBatchDelay is how long the background batcher waits for a batch to fill before sending it.
+	// A batch is sent as soon as it reaches MaxBatchCount or MaxBatchBytes, and otherwise the
+	// messages waiting are sent every MaxBatchDelay, so none waits longer than that. Defaults to 0,
+	// which sends the messages already buffered without waiting for more: the lowest latency, but
+	// smaller batches at low rates.
+	MaxBatchDelay time.Duration
+	// PopDeadline, if set, bounds the total time of each Pop and PopBatch, including the long poll,
+	// retries of failed requests by the AWS SDK and the delete. Once it passes they return
+	// context.DeadlineExceeded instead of retrying further, and the long poll is shortened so that an
+	// empty queue still returns nil in time. PopContext and PopBatchContext also honor any earlier
+	// deadline of their context.
+	PopDeadline time.Duration
+	// EmptyReceiveDelay is the minimum time between calls to Peek or Pop after one finds the queue
+	// empty. Each call already long polls for up to 20 seconds, but calling Pop in a tight loop on an
+	// empty queue still makes a request every 20 seconds per caller; the delay bounds that cost.
+	// Consumers that poll continuously should prefer Consume or Process.
+	EmptyReceiveDelay time.Duration
+	// UserAgent is appended to the User-Agent header of every request, after the identifier of this
+	// package, so that requests from an application can be recognized in CloudTrail and by AWS
+	// support. For example "billing-service/1.2".
+	UserAgent string
+	// QueueDelaySeconds is the delivery delay applied to every message sent to the queue, between 0
+	// and 900 seconds.
+	QueueDelaySeconds int
+	// ReceiveAttemptIDs sets a new ReceiveRequestAttemptId on every receive from a FIFO queue. The
+	// AWS SDK retries failed requests with the same ID, so if a receive succeeded but its response
+	// was lost, for example to a network error, the retry returns the same messages instead of
+	// leaving them, and the messages behind them in their group, locked until the visibility timeout
+	// expires. Ignored for standard queues.
+	ReceiveAttemptIDs bool
+	// MessageAttributeNames are the message attributes returned with received messages. Names may
+	// end in ".*" to request every attribute with a prefix. Defaults to all attributes. With
+	// Compress set, the attribute marking compressed bodies is requested as well.
+	MessageAttributeNames []string
+	// StrictAttributes makes Validate return an error for an invalid name in MessageAttributeNames.
+	// Otherwise SQS accepts the name and silently returns no attribute for it.
+	StrictAttributes bool
+	// HashDeduplicationID sets the deduplication ID of each message sent to a FIFO queue to the
+	// SHA-256 of its body, so identical bodies sent within the 5 minute deduplication window are
+	// only delivered once, without enabling content-based deduplication on the queue. Use
+	// InsertWithDeduplicationID to set a different ID. Ignored for standard queues.
+	HashDeduplicationID bool
+	// Codec encodes and decodes the values passed to InsertValue and PopValue. Defaults to RawCodec.
+	Codec Codec
+	// UseFIPSEndpoint sends requests to the FIPS 140-2 validated SQS endpoint of the region. Only
+	// some US regions have one; GovCloud endpoints are always FIPS validated.
+	UseFIPSEndpoint bool
+	// UseDualStack sends requests to the SQS endpoint of the region that supports both IPv4 and
+	// IPv6.
+	UseDualStack bool
+	// StrictCreate makes NewClient return a *MismatchError if the queue already exists with
+	// attributes that differ from those requested by the Config, as reported by VerifyAttributes.
+	// Without it, a queue that already exists is used as it is, even if its attributes differ.
+	StrictCreate bool
+	// MinimalReceive requests no system or message attributes when receiving, only bodies, which
+	// makes responses smaller for consumers that only read the body. Helpers that read attributes,
+	// such as SentTime and ReceiveCount, find nothing on messages received this way, and Consume
+	// options that depend on attributes have no effect. It cannot be used with Compress, which marks
+	// compressed bodies with an attribute.
+	MinimalReceive bool
+	// PublishCloudWatch publishes the approximate number of visible, in-flight and delayed messages,
+	// and the counters reported to Metrics, such as messages received and deleted, to CloudWatch
+	// using the CloudWatch client every CloudWatchInterval. Metrics have a QueueName dimension. Call
+	// Close to stop publishing.
+	PublishCloudWatch bool
+	// CloudWatch is the client used when PublishCloudWatch is set.
+	CloudWatch CloudWatchAPI
+	// CloudWatchInterval is how often metrics are published to CloudWatch. Defaults to 1 minute.
+	CloudWatchInterval time.Duration
+	// CloudWatchNamespace is the CloudWatch namespace to publish to. Defaults to "arowden-sqs".
+	CloudWatchNamespace string
+	// S3 is the client ArchiveToS3 writes with. Set it when the bucket is in a different region or
+	// account from the queue. Defaults, for a Client created with NewClient, to an S3 client using
+	// the same session as the queue.
+	S3 S3API
+	// ReplayBufferSize, if greater than 0, keeps the bodies and message attributes of this many of
+	// the most recently delivered messages in memory so that Replay can insert them again, for
+	// example after fixing a consumer that mishandled them. The buffer holds up to this many
+	// messages of up to 256 KB each, so size it for the memory available. It belongs to the Client:
+	// it is not shared with other processes and is lost when the process exits.
+	ReplayBufferSize int
+	// Clock, if set, is used instead of the system clock wherever the Client reads the time, so tests
+	// can control message age, expiry and receipt handle tracking.
+	Clock Clock
+}
+
+// Validate returns an error if the configuration is not valid.
+func (c Config) Validate() error {
+	if err := validateVisibilityTimeout(c.VisibilityTimeoutSeconds); err != nil {
+		return err
+	}
+
+	switch c.DeduplicationScope {
+	case "", "messageGroup", "queue":
+	default:
+		return fmt.Errorf("sqs: invalid DeduplicationScope %q", c.DeduplicationScope)
+	}
+
+	switch c.FifoThroughputLimit {
+	case "", "perMessageGroupId", "perQueue":
+	default:
+		return fmt.Errorf("sqs: invalid FifoThroughputLimit %q", c.FifoThroughputLimit)
+	}
+
+	if !c.isFIFO() && (c.DeduplicationScope != "" || c.FifoThroughputLimit != "") {
+		return errors.New("sqs: DeduplicationScope and FifoThroughputLimit require a FIFO queue")
+	}
+
+	if err := validateDelay(c.QueueDelaySeconds); err != nil {
+		return err
+	}
+
+	if c.Policy != "" && !json.Valid([]byte(c.Policy)) {
+		return errors.New("sqs: Policy is not valid JSON")
+	}
+
+	if err := validateAttributes(toMessageAttributes(c.DefaultAttributes)); err != nil {
+		return err
+	}
+
+	if c.ReplayBufferSize < 0 {
+		return fmt.Errorf("sqs: ReplayBufferSize %d must not be negative", c.ReplayBufferSize)
+	}
+
+	if c.BatchRetries < 0 {
+		return fmt.Errorf("sqs: BatchRetries %d must not be negative", c.BatchRetries)
+	}
+
+	if c.MaxBatchCount < 0 || c.MaxBatchCount > MaxBatchSize {
+		return fmt.Errorf("sqs: MaxBatchCount %d must be between 1 and %d", c.MaxBatchCount, MaxBatchSize)
+	}
+
+	if c.MaxBatchBytes < 0 || c.MaxBatchBytes > maxBatchBytes {
+		return fmt.Errorf("sqs: MaxBatchBytes %d must be at most %d", c.MaxBatchBytes, maxBatchBytes)
+	}
+
+	if c.PublishCloudWatch && c.CloudWatch == nil {
+		return errNoCloudWatch
+	}
+
+	if c.MinimalReceive && c.Compress {
+		return errors.New("sqs: MinimalReceive cannot be used with Compress")
+	}
+
+	if err := c.validateEndpoint(); err != nil {
+		return err
+	}
+
+	if c.StrictAttributes {
+		for _, name := range c.MessageAttributeNames {
+			if err := validateAttributeSelector(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.MessageRetentionSeconds != 0 &&
+		(c.MessageRetentionSeconds < 60 || c.MessageRetentionSeconds > 1209600) {
+		return errors.New("sqs: MessageRetentionSeconds must be between 60 and 1209600")
+	}
+
+	return nil
+}
+
+// isFIFO reports whether the queue is a FIFO queue, which AWS requires to be named with a .fifo
+// suffix.
+func (c Config) isFIFO() bool {
+	return strings.HasSuffix(c.Name, fifoSuffix)
 }
 
 // awsAPI interface can be used by a SQS backed queue and the mock queue for testing/development.
 type queueClient interface {
 	SendMessage(*sqs.SendMessageInput) (*sqs.SendMessageOutput, error)
+	SendMessageWithContext(aws.Context, *sqs.SendMessageInput, ...request.Option) (*sqs.SendMessageOutput, error)
 	SendMessageBatch(*sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error)
+	SendMessageBatchWithContext(aws.Context, *sqs.SendMessageBatchInput, ...request.Option) (*sqs.SendMessageBatchOutput, error)
 	DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+	DeleteMessageWithContext(aws.Context, *sqs.DeleteMessageInput, ...request.Option) (*sqs.DeleteMessageOutput, error)
 	DeleteMessageBatch(*sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error)
+	DeleteMessageBatchWithContext(aws.Context, *sqs.DeleteMessageBatchInput, ...request.Option) (*sqs.DeleteMessageBatchOutput, error)
 	GetQueueAttributes(*sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributes(*sqs.SetQueueAttributesInput) (*sqs.SetQueueAttributesOutput, error)
 	PurgeQueue(*sqs.PurgeQueueInput) (*sqs.PurgeQueueOutput, error)
 	ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
+	ReceiveMessageWithContext(aws.Context, *sqs.ReceiveMessageInput, ...request.Option) (*sqs.ReceiveMessageOutput, error)
 	CreateQueue(*sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error)
 	DeleteQueue(*sqs.DeleteQueueInput) (*sqs.DeleteQueueOutput, error)
 	GetQueueUrl(*sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error)
+	ChangeMessageVisibility(*sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error)
+	ChangeMessageVisibilityBatch(*sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error)
+	ListQueues(*sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error)
+	CreateQueueWithContext(aws.Context, *sqs.CreateQueueInput, ...request.Option) (*sqs.CreateQueueOutput, error)
+	DeleteQueueWithContext(aws.Context, *sqs.DeleteQueueInput, ...request.Option) (*sqs.DeleteQueueOutput, error)
+	GetQueueUrlWithContext(aws.Context, *sqs.GetQueueUrlInput, ...request.Option) (*sqs.GetQueueUrlOutput, error)
+	ListQueuesWithContext(aws.Context, *sqs.ListQueuesInput, ...request.Option) (*sqs.ListQueuesOutput, error)
 }
 
 type Client struct {
-	config Config
-	client queueClient
-	url    string
+	config        Config
+	client        queueClient
+	url           string
+	urlMu         sync.RWMutex
+	urlGen        int
+	quarantineURL string
+	handles       handleTracker
+	breaker       *breaker
+	async         *asyncInserter
+	emptyThrottle emptyThrottle
+	s3            S3API
+	cloudWatch    *cloudWatchPublisher
+	clock         Clock
+	replay        *replayBuffer
 }
 
 // NewQueue creates a new Client.
 func NewClient(config Config) (*Client, error) {
-	c := &Client{config: config}
-	s, err := session.NewSession(&aws.Config{Region: &c.config.Region})
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
-	c.client = sqs.New(s)
-	err = c.createQueue()
+	s, err := newSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("sqs: create session: %w", err)
+	}
+
+	_, err = sts.New(s).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, &CredentialsError{Err: err}
+	}
+
+	c, err := newClient(config, sqs.New(s, config.sqsConfig()))
 	if err != nil {
 		return nil, err
 	}
 
+	if c.s3 == nil {
+		c.s3 = s3.New(s)
+	}
+	return c, nil
+}
+
+// CredentialsError is returned by NewClient when the AWS credentials could not be verified, before
+// any attempt is made to create the queue. Errors creating the queue are reported separately, so a
+// CredentialsError means the credentials or region are misconfigured rather than that the
+// credentials lack SQS permissions.
+type CredentialsError struct {
+	Err error
+}
+
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("sqs: verify credentials: %v", e.Err)
+}
+
+// Unwrap returns the error from AWS.
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// NewMockClient creates a Client backed by mock instead of AWS, for use in tests. Unless
+// config.Clock is set, the Client reads the time from the clock of mock; see SetClock.
+func NewMockClient(config Config, mock *MockAPIService) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Clock == nil {
+		mock.mu.Lock()
+		config.Clock = mock.clock
+		mock.mu.Unlock()
+	}
+
+	return newClient(config, mock)
+}
+
+// newClient creates a Client that uses client to call SQS.
+func newClient(config Config, client queueClient) (*Client, error) {
+	c := &Client{config: config, client: client, clock: config.Clock, s3: config.S3}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+	c.breaker = &breaker{
+		threshold: config.BreakerThreshold,
+		cooldown:  config.BreakerCooldown,
+		metrics:   c.metrics(),
+		now:       c.now,
+	}
+	if config.ReplayBufferSize > 0 {
+		c.replay = &replayBuffer{size: config.ReplayBufferSize}
+	}
+
+	err := c.createQueue()
+	if err != nil {
+		return nil, fmt.Errorf("sqs: create queue %s: %w", c.config.Name, err)
+	}
+
 	c.url, err = queueURL(c.config.Name, c.client)
-	return c, err
+	if err != nil {
+		return nil, fmt.Errorf("sqs: get queue url %s: %w", c.config.Name, err)
+	}
+
+	if c.config.StrictCreate {
+		mismatches, err := c.VerifyAttributes()
+		if err != nil {
+			return nil, fmt.Errorf("sqs: verify queue attributes %s: %w", c.config.Name, err)
+		}
+
+		if len(mismatches) > 0 {
+			return nil, &MismatchError{Mismatches: mismatches}
+		}
+	}
+
+	if c.config.QuarantineQueue != "" {
+		c.quarantineURL, err = queueURL(c.config.QuarantineQueue, c.client)
+		if err != nil {
+			return nil, fmt.Errorf("sqs: get queue url %s: %w", c.config.QuarantineQueue, err)
+		}
+	}
+
+	if c.config.InsertBufferSize > 0 {
+		c.async = newAsyncInserter(c, c.config.InsertBufferSize)
+	}
+
+	if c.config.PublishCloudWatch {
+		c.cloudWatch = newCloudWatchPublisher(c)
+		c.breaker.metrics = c.cloudWatch
+	}
+
+	return c, nil
+}
+
+// userAgent identifies requests made by this package in the User-Agent header.
+const userAgent = "arowden-sqs"
+
+// newSession creates the AWS session for a Client.
+func newSession(config Config) (*session.Session, error) {
+	awsConfig := aws.Config{Region: &config.Region}
+	var s *session.Session
+	var err error
+	if config.Profile == "" {
+		s, err = session.NewSession(&awsConfig)
+	} else {
+		s, err = session.NewSessionWithOptions(session.Options{
+			Config:            awsConfig,
+			Profile:           config.Profile,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.Handlers.Build.PushBack(request.MakeAddToUserAgentFreeFormHandler(userAgent))
+	if config.UserAgent != "" {
+		s.Handlers.Build.PushBack(request.MakeAddToUserAgentFreeFormHandler(config.UserAgent))
+	}
+
+	return s, nil
 }
 
 // DeleteQueue deletes the specified queue from AWS.
 func (c *Client) DeleteQueue() error {
-	req := &sqs.DeleteQueueInput{QueueUrl: &c.url}
+	req := &sqs.DeleteQueueInput{QueueUrl: c.currentURL()}
 	_, err := c.client.DeleteQueue(req)
 	return err
 }
 
-// Insert inserts a string into the queue.
+// DeleteQueueIfExists deletes the specified queue from AWS. Unlike DeleteQueue, it does not return
+// an error if the queue has already been deleted, which makes it safe to use in cleanup code.
+func (c *Client) DeleteQueueIfExists() error {
+	err := c.DeleteQueue()
+	if isQueueNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Insert inserts a string into the queue. For FIFO queues ErrMissingGroupID is returned; use
+// InsertWithGroup instead. If Config.InsertBufferSize is set, the string is checked, buffered and
+// sent in the background, and errors sending it are returned by a later call to Insert or Close;
+// a call that returns such an error has not buffered its string.
 func (c *Client) Insert(input string) error {
-	request := &sqs.SendMessageInput{
+	return c.InsertContext(context.Background(), input)
+}
+
+// InsertContext is like Insert but the request can be canceled with ctx, and measurements are
+// attributed to the request ID of ctx, if it has one; see WithRequestID. Buffered strings are sent
+// in the background without ctx.
+func (c *Client) InsertContext(ctx context.Context, input string) error {
+	if c.config.isFIFO() {
+		return ErrMissingGroupID
+	}
+
+	if c.async != nil {
+		if _, _, err := c.prepareMessage(input, nil); err != nil {
+			return err
+		}
+
+		return c.async.insert(input)
+	}
+
+	return c.sendMessageContext(ctx, &sqs.SendMessageInput{
 		MessageBody: &input,
-		QueueUrl:    &c.url,
+		QueueUrl:    c.currentURL(),
+	})
+}
+
+// InsertWithGroup inserts a string into a FIFO queue as part of the given message group. Messages
+// in the same group are received in the order they were inserted.
+func (c *Client) InsertWithGroup(input, groupID string) error {
+	if groupID == "" {
+		return ErrMissingGroupID
 	}
 
-	_, err := c.client.SendMessage(request)
-	return err
+	return c.sendMessage(&sqs.SendMessageInput{
+		MessageBody:    &input,
+		MessageGroupId: &groupID,
+		QueueUrl:       c.currentURL(),
+	})
 }
 
-// InsertBatch inserts up to 10 strings into the queue.
+// InsertWithAttributes inserts a string into the queue with up to 10 message attributes.
+func (c *Client) InsertWithAttributes(input string, attrs map[string]Attribute) error {
+	if c.config.isFIFO() {
+		return ErrMissingGroupID
+	}
+
+	return c.sendMessage(&sqs.SendMessageInput{
+		MessageAttributes: toMessageAttributes(attrs),
+		MessageBody:       &input,
+		QueueUrl:          c.currentURL(),
+	})
+}
+
+// InsertBatch inserts up to 10 strings into the queue. ErrBatchTooLarge is returned for more than
+// 10, and ErrMissingGroupID for FIFO queues. If some strings are still not inserted after
+// Config.BatchRetries retries, a *BatchError keyed by their index in inputs is returned.
 func (c *Client) InsertBatch(inputs []string) error {
-	entries := makeBatchRequestEntries(inputs)
+	return c.InsertBatchContext(context.Background(), inputs)
+}
+
+// InsertBatchContext is like InsertBatch but the requests can be canceled with ctx, and
+// measurements are attributed to the request ID of ctx, if it has one.
+func (c *Client) InsertBatchContext(ctx context.Context, inputs []string) error {
+	results, err := c.insertBatchResults(ctx, inputs)
+	if err != nil {
+		return err
+	}
+
+	return newInsertBatchError(results)
+}
+
+// InsertBatchResults is like InsertBatch but also returns the result of inserting each string, in
+// the same order as inputs. The error is only non-nil if the whole request failed; the Err field of
+// each result reports whether that string was inserted, after any retries.
+func (c *Client) InsertBatchResults(inputs []string) ([]BatchResult, error) {
+	return c.insertBatchResults(context.Background(), inputs)
+}
+
+// insertBatchResults is like InsertBatchResults but bounded by ctx.
+func (c *Client) insertBatchResults(ctx context.Context, inputs []string) ([]BatchResult, error) {
+	if c.config.isFIFO() {
+		return nil, ErrMissingGroupID
+	}
+
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	if len(inputs) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	entries, err := c.makeBatchRequestEntries(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sendBatchWithRetries(ctx, entries)
+}
+
+// sendBatch sends a batch request and returns the result of each entry in request order.
+func (c *Client) sendBatch(ctx context.Context, entries []*sqs.SendMessageBatchRequestEntry) ([]BatchResult, error) {
 	request := &sqs.SendMessageBatchInput{
 		Entries:  entries,
-		QueueUrl: &c.url,
+		QueueUrl: c.currentURL(),
 	}
 
-	_, err := c.client.SendMessageBatch(request)
-	return err
+	start := c.now()
+	var resp *sqs.SendMessageBatchOutput
+	err := c.guard(ctx, func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			var err error
+			resp, err = c.client.SendMessageBatchWithContext(ctx, request)
+			return err
+		})
+	})
+	c.observe(ctx, sendBatchOperation, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	results := batchResults(entries, resp)
+	sent := 0
+	for _, r := range results {
+		if r.Err == nil {
+			sent++
+		}
+	}
+	c.metricsFor(ctx).Count(sentMetric, sent)
+	return results, nil
 }
 
 // Delete takes a single Item and removes it from the queue.
 func (c *Client) Delete(msg *sqs.Message) error {
+	return c.DeleteContext(context.Background(), msg)
+}
+
+// DeleteContext is like Delete but the request can be canceled with ctx, and measurements are
+// attributed to the request ID of ctx, if it has one.
+func (c *Client) DeleteContext(ctx context.Context, msg *sqs.Message) error {
+	if err := validateReceiptHandle(msg); err != nil {
+		return err
+	}
+
 	request := &sqs.DeleteMessageInput{
-		QueueUrl:      &c.url,
+		QueueUrl:      c.currentURL(),
 		ReceiptHandle: msg.ReceiptHandle,
 	}
 
-	_, err := c.client.DeleteMessage(request)
+	start := c.now()
+	err := c.guard(ctx, func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			_, err := c.client.DeleteMessageWithContext(ctx, request)
+			return err
+		})
+	})
+	c.observe(ctx, deleteOperation, start, err)
+	if aerr, ok := err.(awserr.Error); ok && isReceiptHandleExpired(aerr.Code(), aerr.Message()) {
+		c.handles.remove(msg)
+		return fmt.Errorf("%w: %v", ErrReceiptHandleExpired, err)
+	}
+
+	if err == nil {
+		c.handles.remove(msg)
+		c.metricsFor(ctx).Count(deletedMetric, 1)
+	}
+
 	return err
 }
 
-// DeleteBatch deletes a batch of up to 10 Items.
+// DeleteBatch deletes a batch of up to 10 Items. ErrBatchTooLarge is returned for more than 10. If
+// the receipt handles of some messages had expired, the other messages are still deleted and an
+// error wrapping ErrReceiptHandleExpired is returned.
 func (c *Client) DeleteBatch(items []*sqs.Message) error {
+	return c.DeleteBatchContext(context.Background(), items)
+}
+
+// DeleteBatchContext is like DeleteBatch but the request can be canceled with ctx, and measurements
+// are attributed to the request ID of ctx, if it has one.
+func (c *Client) DeleteBatchContext(ctx context.Context, items []*sqs.Message) error {
+	_, _, expired, err := c.deleteBatch(ctx, items)
+	if err == nil && len(expired) > 0 {
+		err = expiredError(len(expired))
+	}
+
+	return err
+}
+
+// deleteBatch deletes a batch of up to 10 messages and returns those that AWS failed to delete:
+// failed may succeed if retried, rejected failed because of a problem with the entry that retrying
+// would not fix, and expired had receipt handles that had expired, which can never be deleted.
+func (c *Client) deleteBatch(ctx context.Context, items []*sqs.Message) (failed, rejected, expired []*sqs.Message, err error) {
+	if len(items) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	if len(items) > MaxBatchSize {
+		return nil, nil, nil, ErrBatchTooLarge
+	}
+
+	for _, msg := range items {
+		if err := validateReceiptHandle(msg); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	entries := makeDeleteMsgBatchRequestEntry(items)
+	defer entries.release()
 	request := &sqs.DeleteMessageBatchInput{
-		Entries:  entries,
-		QueueUrl: &c.url,
+		Entries:  entries.ptrs[:len(items)],
+		QueueUrl: c.currentURL(),
 	}
 
-	_, err := c.client.DeleteMessageBatch(request)
-	return err
+	start := c.now()
+	var resp *sqs.DeleteMessageBatchOutput
+	err = c.guard(ctx, func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			var err error
+			resp, err = c.client.DeleteMessageBatchWithContext(ctx, request)
+			return err
+		})
+	})
+	c.observe(ctx, deleteBatchOperation, start, err)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if resp == nil {
+		resp = &sqs.DeleteMessageBatchOutput{}
+	}
+
+	c.metricsFor(ctx).Count(deletedMetric, len(items)-len(resp.Failed))
+	if len(resp.Failed) == 0 {
+		c.handles.remove(items...)
+		return nil, nil, nil, nil
+	}
+
+	// Entries are identified by their index, since a message received twice would otherwise give
+	// two entries the same ID.
+	failures := make([]*sqs.BatchResultErrorEntry, len(items))
+	for _, f := range resp.Failed {
+		if i, err := strconv.Atoi(aws.StringValue(f.Id)); err == nil && i >= 0 && i < len(items) {
+			failures[i] = f
+		}
+	}
+
+	var done []*sqs.Message
+	for i, msg := range items {
+		f := failures[i]
+		switch {
+		case f == nil:
+			done = append(done, msg)
+		case isReceiptHandleExpired(aws.StringValue(f.Code), aws.StringValue(f.Message)):
+			done = append(done, msg)
+			expired = append(expired, msg)
+		case aws.BoolValue(f.SenderFault):
+			rejected = append(rejected, msg)
+		default:
+			failed = append(failed, msg)
+		}
+	}
+
+	c.handles.remove(done...)
+	return failed, rejected, expired, nil
 }
 
 // Peek returns an Item from the queue but does not delete it. If the Item is not deleted within the
 // visibility timeout it could be received again or received by another instance of the queue. If
-// the queue is empty nil is returned.
+// the queue is empty nil is returned. If Config.BodyValidator is set and the message fails
+// validation, a *QuarantineError is returned instead of the message. If Config.EmptyReceiveDelay is
+// set and the previous call found the queue empty, Peek first waits for the rest of the delay.
 func (c *Client) Peek() (*sqs.Message, error) {
-	resp, err := c.receiveNitems(1)
+	return c.peek(context.Background())
+}
+
+// peek is like Peek but bounded by ctx.
+func (c *Client) peek(ctx context.Context) (*sqs.Message, error) {
+	if err := c.emptyThrottle.wait(ctx, c.config.EmptyReceiveDelay, c.now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.receiveNitemsContext(ctx, 1)
 	if err != nil {
 		return nil, err
 	}
 
+	c.emptyThrottle.record(len(resp.Messages) == 0, c.now())
 	if len(resp.Messages) == 0 {
 		return nil, nil
 	}
 
-	return resp.Messages[0], nil
+	msg := resp.Messages[0]
+	if err := c.validate(msg); err != nil {
+		return nil, err
+	}
+
+	c.retain(resp.Messages)
+	return msg, nil
 }
 
 // PeekBatch returns up to 10 Items from the queue put does not delete them. If the Item is not
 // deleted within the visibility timeout it could be received again or received by another instance
-// of the queue. If the queue is empty nil is returned.
+// of the queue. If the queue is empty nil is returned. Messages that fail Config.BodyValidator are
+// left out, and the error for the first of them is returned along with the valid messages.
 func (c *Client) PeekBatch() ([]*sqs.Message, error) {
-	resp, err := c.receiveNitems(10)
+	resp, err := c.receiveNitemsContext(context.Background(), MaxBatchSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Messages, nil
+	return c.deliverValid(resp.Messages)
 }
 
-// Pop retrieves an Item from the queue, deletes it from the queue and returns it.
-func (c *Client) Pop() (*sqs.Message, error) {
-	msg, err := c.Peek()
+// ReceiveResult is the result of receiving a batch of messages.
+type ReceiveResult struct {
+	// Messages received, which are not deleted from the queue.
+	Messages []*sqs.Message
+	// Requested is the number of messages that were asked for.
+	Requested int
+}
+
+// Truncated reports whether fewer messages were received than were requested. SQS often returns
+// fewer messages than requested even when more are available, because each receive only samples a
+// subset of its servers, so a truncated result does not mean the queue is nearly empty. Callers that
+// want to drain the queue quickly can receive again immediately when the result is truncated but
+// not empty.
+func (r *ReceiveResult) Truncated() bool {
+	return len(r.Messages) < r.Requested
+}
+
+// Receive is like PeekBatch but receives up to n messages, which must be between 1 and 10, and
+// reports how many were requested. Like PeekBatch, it returns a result with the valid messages along
+// with the error for the first message that failed Config.BodyValidator.
+func (c *Client) Receive(n int) (*ReceiveResult, error) {
+	if n < 1 || n > MaxBatchSize {
+		return nil, fmt.Errorf("sqs: cannot receive %d messages, must be between 1 and %d", n, MaxBatchSize)
+	}
+
+	resp, err := c.receiveNitemsContext(context.Background(), n)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Delete(msg)
-	if err != nil {
+	msgs, err := c.deliverValid(resp.Messages)
+	return &ReceiveResult{Messages: msgs, Requested: n}, err
+}
+
+// Pop retrieves an Item from the queue, deletes it from the queue and returns it. The message is
+// deleted before it is processed, so it is lost if processing fails; use HandleNext to delete it
+// only after it has been processed successfully.
+func (c *Client) Pop() (*sqs.Message, error) {
+	return c.PopContext(context.Background())
+}
+
+// PopContext is like Pop but the requests can be canceled with ctx, and measurements are
+// attributed to the request ID of ctx, if it has one.
+func (c *Client) PopContext(ctx context.Context) (*sqs.Message, error) {
+	ctx, cancel := c.popDeadline(ctx)
+	defer cancel()
+
+	msg, err := c.peek(ctx)
+	if err != nil || msg == nil {
 		return nil, err
 	}
+
+	err = c.DeleteContext(ctx, msg)
+	if err != nil {
+		return nil, deadlineError(ctx, err)
+	}
 	return msg, err
 }
 
 // PopBatch retrieves a batch of up to 10 messages from the queue, deletes them from the queue and
-// returns them.
+// returns them. Messages that AWS fails to delete are returned as if they had been deleted; use
+// PopBatchResults to tell them apart. Messages that fail Config.BodyValidator are not deleted or
+// returned, and the error for the first of them is returned along with the deleted messages.
 func (c *Client) PopBatch() ([]*sqs.Message, error) {
-	var Msgs []*sqs.Message
-	Msgs, err := c.PeekBatch()
+	return c.PopBatchContext(context.Background())
+}
+
+// PopBatchContext is like PopBatch but the requests can be canceled with ctx, and measurements are
+// attributed to the request ID of ctx, if it has one.
+func (c *Client) PopBatchContext(ctx context.Context) ([]*sqs.Message, error) {
+	ctx, cancel := c.popDeadline(ctx)
+	defer cancel()
+
+	resp, err := c.receiveNitemsContext(ctx, MaxBatchSize)
 	if err != nil {
-		return Msgs, err
+		return nil, err
+	}
+
+	msgs, invalid := c.deliverValid(resp.Messages)
+	if len(msgs) == 0 {
+		return nil, invalid
 	}
 
-	err = c.DeleteBatch(Msgs)
-	return Msgs, err
+	if err := c.DeleteBatchContext(ctx, msgs); err != nil {
+		return msgs, deadlineError(ctx, err)
+	}
+	return msgs, invalid
 }
 
-// ApproximateLen returns approximately the number of items in the queue. This attribute can lag the
-// actual queue size by up to 30 seconds.
-func (c *Client) ApproximateLen() int {
-	queueLenAttribute := aws.String("ApproximateNumberOfMessages")
-	request := &sqs.GetQueueAttributesInput{
-		QueueUrl:       &c.url,
-		AttributeNames: []*string{queueLenAttribute},
+// popDeadline returns ctx bounded by Config.PopDeadline, if it is set.
+func (c *Client) popDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.PopDeadline <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, c.config.PopDeadline)
+}
+
+// deadlineError returns the error of ctx if it is done, since err is then only a consequence of
+// it, such as the AWS SDK reporting the request as canceled. Otherwise it returns err.
+func deadlineError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// PopResult is a message retrieved by PopBatchResults.
+type PopResult struct {
+	Message *sqs.Message
+	// Deleted is false if the message could not be deleted and will become visible in the queue
+	// again, so it may be received again.
+	Deleted bool
+}
+
+// PopBatchResults is like PopBatch but reports for each message whether it was deleted, so that a
+// partially failed delete can be handled safely. If the delete request fails entirely, the messages
+// are returned with Deleted false along with the error.
+func (c *Client) PopBatchResults() ([]PopResult, error) {
+	msgs, invalid := c.PeekBatch()
+	if len(msgs) == 0 {
+		return nil, invalid
+	}
+
+	results := make([]PopResult, len(msgs))
+	for i, msg := range msgs {
+		results[i].Message = msg
+	}
+
+	failed, rejected, expired, err := c.deleteBatch(context.Background(), msgs)
+	if err != nil {
+		return results, err
+	}
+
+	notDeleted := make(map[*sqs.Message]bool, len(failed)+len(rejected)+len(expired))
+	for _, msg := range append(append(failed, rejected...), expired...) {
+		notDeleted[msg] = true
 	}
 
-	response, _ := c.client.GetQueueAttributes(request)
-	lengthAttribute := response.Attributes[*queueLenAttribute]
-	length, _ := strconv.Atoi(*lengthAttribute)
-	return length
+	for i := range results {
+		results[i].Deleted = !notDeleted[results[i].Message]
+	}
+
+	return results, invalid
+}
+
+// ApproximateLen returns approximately the number of items in the queue. This attribute can lag the
+// actual queue size by up to 30 seconds. An error is returned if the request fails or the response
+// has no ApproximateNumberOfMessages attribute.
+func (c *Client) ApproximateLen() (int, error) {
+	return c.intAttribute(sqs.QueueAttributeNameApproximateNumberOfMessages)
 }
 
 // Purge clears the contents of the queue.
 func (c *Client) Purge() error {
 	request := &sqs.PurgeQueueInput{
-		QueueUrl: &c.url,
+		QueueUrl: c.currentURL(),
 	}
 
-	_, err := c.client.PurgeQueue(request)
-	return err
+	return c.guard(context.Background(), func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			_, err := c.client.PurgeQueue(request)
+			return err
+		})
+	})
 }
 
 // receiveNitems returns specified number of items. Must be between 1 - 10.
 func (c *Client) receiveNitems(n int) (*sqs.ReceiveMessageOutput, error) {
-	result, err := c.client.ReceiveMessage(&sqs.ReceiveMessageInput{
+	return c.receiveNitemsContext(context.Background(), n)
+}
+
+// receiveNitemsContext is like receiveNitems but can be canceled with ctx.
+func (c *Client) receiveNitemsContext(ctx context.Context, n int) (*sqs.ReceiveMessageOutput, error) {
+	return c.receive(ctx, c.receiveInput(n))
+}
+
+// receiveInput returns the default request for receiving n messages.
+func (c *Client) receiveInput(n int) *sqs.ReceiveMessageInput {
+	if c.config.MinimalReceive {
+		return &sqs.ReceiveMessageInput{
+			QueueUrl:            c.currentURL(),
+			MaxNumberOfMessages: aws.Int64(int64(n)),
+			VisibilityTimeout:   aws.Int64(int64(c.config.VisibilityTimeoutSeconds)),
+			WaitTimeSeconds:     aws.Int64(20),
+		}
+	}
+
+	return &sqs.ReceiveMessageInput{
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
+			aws.String(awsTraceHeaderAttribute),
+			aws.String(sqs.MessageSystemAttributeNameSequenceNumber),
+			aws.String(sqs.MessageSystemAttributeNameSenderId),
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+			aws.String(sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp),
+			aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
 		},
-		MessageAttributeNames: []*string{
-			aws.String(sqs.QueueAttributeNameAll),
-		},
-		QueueUrl:            &c.url,
-		MaxNumberOfMessages: aws.Int64(int64(n)),
-		VisibilityTimeout:   aws.Int64(int64(c.config.VisibilityTimeoutSeconds)),
-		WaitTimeSeconds:     aws.Int64(20),
+		MessageAttributeNames: c.messageAttributeNames(),
+		QueueUrl:              c.currentURL(),
+		MaxNumberOfMessages:   aws.Int64(int64(n)),
+		VisibilityTimeout:     aws.Int64(int64(c.config.VisibilityTimeoutSeconds)),
+		WaitTimeSeconds:       aws.Int64(20),
+	}
+}
+
+// messageAttributeNames returns the message attributes to request on receive. With Compress set,
+// the attribute marking compressed bodies is always requested, since they cannot be decoded without
+// it.
+func (c *Client) messageAttributeNames() []*string {
+	if len(c.config.MessageAttributeNames) == 0 {
+		return []*string{aws.String(allAttributes)}
+	}
+
+	names := aws.StringSlice(c.config.MessageAttributeNames)
+	if c.config.Compress && !requested(names, contentEncodingAttribute) {
+		names = append(names, aws.String(contentEncodingAttribute))
+	}
+
+	return names
+}
+
+// receive sends a receive request, normally one built by receiveInput, decodes the messages and
+// records that they were received: their receipt handles are tracked and they are counted in the
+// received metric.
+func (c *Client) receive(ctx context.Context, input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	fetched := c.now()
+	result, err := c.fetch(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second
+	c.handles.add(result.Messages, fetched, timeout)
+	c.metricsFor(ctx).Count(receivedMetric, len(result.Messages))
+	return result, nil
+}
+
+// fetch is like receive but does not record that the messages were received, for requests such as
+// the one made by IsEmpty that only look at the queue.
+func (c *Client) fetch(ctx context.Context, input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	if c.config.ReceiveAttemptIDs && c.config.isFIFO() {
+		attempt := *input
+		attempt.ReceiveRequestAttemptId = randomID()
+		input = &attempt
+	}
+
+	input = waitWithin(ctx, input)
+	start := c.now()
+	var result *sqs.ReceiveMessageOutput
+	err := c.withURL(&input.QueueUrl, func() error {
+		var err error
+		result, err = c.client.ReceiveMessageWithContext(ctx, input)
+		return err
 	})
-	return result, err
+	c.observe(ctx, receiveOperation, start, err)
+	if ctx.Err() != nil {
+		c.breaker.release()
+	} else {
+		c.breaker.record(err)
+	}
+
+	if err != nil {
+		return nil, deadlineError(ctx, err)
+	}
+
+	if result == nil {
+		result = &sqs.ReceiveMessageOutput{}
+	}
+
+	decoded := result.Messages[:0]
+	for _, msg := range result.Messages {
+		if err := decodeBody(msg); err != nil {
+			c.rejectCorrupt(ctx, msg)
+			continue
+		}
+		decoded = append(decoded, msg)
+	}
+	result.Messages = decoded
+
+	return result, nil
 }
 
-// createQueue creates a new sqs queue in AWS.
+// waitWithin returns input with its long poll shortened, if necessary, to end a second before the
+// deadline of ctx, so that a receive from an empty queue returns no messages rather than failing
+// when the deadline passes.
+func waitWithin(ctx context.Context, input *sqs.ReceiveMessageInput) *sqs.ReceiveMessageInput {
+	deadline, ok := ctx.Deadline()
+	if !ok || input.WaitTimeSeconds == nil {
+		return input
+	}
+
+	max := int64(time.Until(deadline)/time.Second) - 1
+	if max < 0 {
+		max = 0
+	}
+
+	if *input.WaitTimeSeconds <= max {
+		return input
+	}
+
+	shortened := *input
+	shortened.WaitTimeSeconds = aws.Int64(max)
+	return &shortened
+}
+
+// sendMessage encodes the body of request and sends it.
+func (c *Client) sendMessage(request *sqs.SendMessageInput) error {
+	return c.sendMessageContext(context.Background(), request)
+}
+
+// sendMessageContext is like sendMessage but bounded by ctx.
+func (c *Client) sendMessageContext(ctx context.Context, request *sqs.SendMessageInput) error {
+	if request.MessageDeduplicationId == nil {
+		request.MessageDeduplicationId = c.deduplicationID(aws.StringValue(request.MessageBody))
+	}
+
+	body, attrs, err := c.prepareMessage(aws.StringValue(request.MessageBody), request.MessageAttributes)
+	if err != nil {
+		return err
+	}
+
+	request.MessageBody = &body
+	request.MessageAttributes = attrs
+	return c.send(ctx, request)
+}
+
+// send sends request as it is, without the deduplication ID, default attributes or encoding that
+// sendMessageContext adds.
+func (c *Client) send(ctx context.Context, request *sqs.SendMessageInput) error {
+	start := c.now()
+	err := c.guard(ctx, func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			_, err := c.client.SendMessageWithContext(ctx, request)
+			return err
+		})
+	})
+	c.observe(ctx, sendOperation, start, err)
+	if err == nil {
+		c.metricsFor(ctx).Count(sentMetric, 1)
+	}
+
+	return err
+}
+
+// prepareMessage adds the default attributes to a message, encodes its body and validates the
+// result.
+func (c *Client) prepareMessage(body string, attrs map[string]*sqs.MessageAttributeValue) (string, map[string]*sqs.MessageAttributeValue, error) {
+	body, attrs, err := c.encodeBody(body, c.withDefaultAttributes(attrs))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return body, attrs, validateAttributes(attrs)
+}
+
+// createQueue creates the queue in AWS unless a queue with the same name already exists.
 func (c *Client) createQueue() error {
-	req := &sqs.CreateQueueInput{QueueName: &c.config.Name}
+	req := &sqs.CreateQueueInput{
+		Attributes: c.queueAttributes(),
+		QueueName:  &c.config.Name,
+	}
+
 	_, err := c.client.CreateQueue(req)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == sqs.ErrCodeQueueNameExists {
+		// The queue exists with different attributes. NewClient uses it as it is, or reports the
+		// differences if StrictCreate is set.
+		return nil
+	}
+
 	return err
 }
 
+// queueAttributes returns the attributes to create the queue with.
+func (c *Client) queueAttributes() map[string]*string {
+	attrs := make(map[string]*string)
+	if c.config.isFIFO() {
+		attrs[sqs.QueueAttributeNameFifoQueue] = aws.String("true")
+	}
+
+	if c.config.VisibilityTimeoutSeconds != 0 {
+		attrs[sqs.QueueAttributeNameVisibilityTimeout] = aws.String(strconv.Itoa(c.config.VisibilityTimeoutSeconds))
+	}
+
+	if c.config.DeduplicationScope != "" {
+		attrs["DeduplicationScope"] = aws.String(c.config.DeduplicationScope)
+	}
+
+	if c.config.FifoThroughputLimit != "" {
+		attrs["FifoThroughputLimit"] = aws.String(c.config.FifoThroughputLimit)
+	}
+
+	if c.config.MessageRetentionSeconds != 0 {
+		attrs[sqs.QueueAttributeNameMessageRetentionPeriod] =
+			aws.String(strconv.Itoa(c.config.MessageRetentionSeconds))
+	}
+
+	if c.config.QueueDelaySeconds != 0 {
+		attrs[sqs.QueueAttributeNameDelaySeconds] = aws.String(strconv.Itoa(c.config.QueueDelaySeconds))
+	}
+
+	if c.config.Policy != "" {
+		attrs[sqs.QueueAttributeNamePolicy] = aws.String(c.config.Policy)
+	}
+
+	if c.config.KMSMasterKeyID != "" {
+		attrs[sqs.QueueAttributeNameKmsMasterKeyId] = aws.String(c.config.KMSMasterKeyID)
+	}
+
+	return attrs
+}
+
 func queueURL(name string, client queueClient) (string, error) {
+	return queueURLContext(context.Background(), name, client)
+}
+
+// queueURLContext is like queueURL but bounded by ctx.
+func queueURLContext(ctx context.Context, name string, client queueClient) (string, error) {
 	req := &sqs.GetQueueUrlInput{QueueName: &name}
-	res, err := client.GetQueueUrl(req)
-	return *res.QueueUrl, err
+	res, err := client.GetQueueUrlWithContext(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if res == nil {
+		return "", fmt.Errorf("sqs: no URL returned for queue %q", name)
+	}
+
+	return aws.StringValue(res.QueueUrl), nil
+}
+
+// isQueueNotExist reports whether err is the error AWS returns when the queue does not exist.
+func isQueueNotExist(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == sqs.ErrCodeQueueDoesNotExist
 }
 
 // makeBatchRequestEntries takes a slice of string items and returns what can be used as a request
 // to aws to insert the items into the queue.
-func makeBatchRequestEntries(items []string) (entries []*sqs.SendMessageBatchRequestEntry) {
-	for _, item := range items {
-		id := randomID()
+func (c *Client) makeBatchRequestEntries(items []string) (entries []*sqs.SendMessageBatchRequestEntry, err error) {
+	for i, item := range items {
+		body, attrs, err := c.prepareMessage(item, nil)
+		if err != nil {
+			return nil, err
+		}
+
 		newEntry := &sqs.SendMessageBatchRequestEntry{
-			Id:          id,
-			MessageBody: &item,
+			Id:                batchEntryID(i),
+			MessageAttributes: attrs,
+			MessageBody:       aws.String(body),
 		}
 		entries = append(entries, newEntry)
 	}
 
-	return entries
+	return entries, nil
+}
+
+// isReceiptHandleExpired reports whether the error code and message AWS returned for a delete mean
+// the receipt handle had expired. AWS reports this as an invalid receipt handle, as a message that
+// is not in flight, or as an invalid parameter value mentioning the expiry.
+func isReceiptHandleExpired(code, message string) bool {
+	switch code {
+	case sqs.ErrCodeReceiptHandleIsInvalid, sqs.ErrCodeMessageNotInflight:
+		return true
+	case "InvalidParameterValue":
+		return strings.Contains(message, "expired")
+	}
+
+	return false
+}
+
+// expiredError returns the error reported when n messages could not be deleted because their
+// receipt handles had expired.
+func expiredError(n int) error {
+	return fmt.Errorf("%w: %d messages", ErrReceiptHandleExpired, n)
+}
+
+// validateReceiptHandle returns an error if msg has no receipt handle to delete it with.
+func validateReceiptHandle(msg *sqs.Message) error {
+	if msg == nil || aws.StringValue(msg.ReceiptHandle) == "" {
+		var id string
+		if msg != nil {
+			id = aws.StringValue(msg.MessageId)
+		}
+		return fmt.Errorf("%w: message %q", ErrEmptyReceiptHandle, id)
+	}
+
+	return nil
 }
 
-// makeDeleteMsgBatchRequestEntry takes a slice of sqs messages and converts them into a request
-// that will delete all of them as a batch.
-func makeDeleteMsgBatchRequestEntry(items []*sqs.Message) []*sqs.DeleteMessageBatchRequestEntry {
-	var entries []*sqs.DeleteMessageBatchRequestEntry
-	for _, message := range items {
-		newEntry := &sqs.DeleteMessageBatchRequestEntry{
+// makeDeleteMsgBatchRequestEntry takes up to 10 sqs messages and converts them into the entries of
+// a request that will delete all of them as a batch. The entries must be released once the request
+// has been sent.
+func makeDeleteMsgBatchRequestEntry(items []*sqs.Message) *deleteEntries {
+	entries := deleteEntriesPool.Get().(*deleteEntries)
+	for i, message := range items {
+		entries.values[i] = sqs.DeleteMessageBatchRequestEntry{
 			ReceiptHandle: message.ReceiptHandle,
-			Id:            message.MessageId,
+			Id:            &deleteEntryIDs[i],
 		}
-		entries = append(entries, newEntry)
+		entries.ptrs[i] = &entries.values[i]
 	}
 
 	return entries
 }
 
+// batchEntryID returns the ID of the i-th entry of a batch request. It can be replaced with
+// sequentialID to make request IDs predictable in tests.
+var batchEntryID = func(i int) *string {
+	return randomID()
+}
+
+// sequentialID returns i as a string.
+func sequentialID(i int) *string {
+	return aws.String(strconv.Itoa(i))
+}
+
 // randomID generates random string that can be used with messageID and groupID fields.
 func randomID() *string {
 	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
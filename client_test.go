@@ -0,0 +1,310 @@
+package sqs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// testConfig returns a valid Config for a standard queue named name.
+func testConfig(name string) Config {
+	return Config{Name: name, Region: "us-east-1", VisibilityTimeoutSeconds: 30}
+}
+
+// newTestClient returns a Client for config backed by mock, failing the test if it cannot be
+// created.
+func newTestClient(t *testing.T, config Config, mock *MockAPIService) *Client {
+	t.Helper()
+
+	c, err := NewMockClient(config, mock)
+	if err != nil {
+		t.Fatalf("NewMockClient: %v", err)
+	}
+
+	return c
+}
+
+// countingMetrics is a Metrics that records counters, for tests that check what a Client counted.
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *countingMetrics) Count(name string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[name] += n
+}
+
+func (m *countingMetrics) Gauge(string, float64)        {}
+func (m *countingMetrics) Timing(string, time.Duration) {}
+
+// count returns the value of the named counter.
+func (m *countingMetrics) count(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counts[name]
+}
+
+// errQueueNotExist is the error AWS returns for a queue that does not exist.
+var errQueueNotExist = awserr.New(sqs.ErrCodeQueueDoesNotExist, "The specified queue does not exist.", nil)
+
+func TestDeleteQueueIfExists(t *testing.T) {
+	errDenied := awserr.New("AccessDenied", "access denied", nil)
+	tests := []struct {
+		name string
+		fail error
+		want error
+	}{
+		{name: "deleted", fail: nil, want: nil},
+		{name: "already gone", fail: errQueueNotExist, want: nil},
+		{name: "other error", fail: errDenied, want: errDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			c := newTestClient(t, testConfig("orders"), mock)
+			if tt.fail != nil {
+				mock.FailNext("DeleteQueue", tt.fail)
+			}
+
+			if err := c.DeleteQueueIfExists(); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteQueueIfExists() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteQueueReturnsNotExist(t *testing.T) {
+	mock := NewMockAPIService()
+	c := newTestClient(t, testConfig("orders"), mock)
+	mock.FailNext("DeleteQueue", errQueueNotExist)
+
+	if err := c.DeleteQueue(); !isQueueNotExist(err) {
+		t.Errorf("DeleteQueue() = %v, want QueueDoesNotExist", err)
+	}
+}
+
+func TestFIFOInsertRequiresGroupID(t *testing.T) {
+	tests := []struct {
+		name   string
+		insert func(c *Client) error
+		want   error
+	}{
+		{name: "Insert", insert: func(c *Client) error { return c.Insert("body") }, want: ErrMissingGroupID},
+		{
+			name:   "InsertWithAttributes",
+			insert: func(c *Client) error { return c.InsertWithAttributes("body", nil) },
+			want:   ErrMissingGroupID,
+		},
+		{name: "InsertBatch", insert: func(c *Client) error { return c.InsertBatch([]string{"body"}) }, want: ErrMissingGroupID},
+		{name: "empty group", insert: func(c *Client) error { return c.InsertWithGroup("body", "") }, want: ErrMissingGroupID},
+		{name: "InsertWithGroup", insert: func(c *Client) error { return c.InsertWithGroup("body", "orders") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			c := newTestClient(t, testConfig("orders.fifo"), mock)
+
+			if err := tt.insert(c); err != tt.want {
+				t.Fatalf("insert error = %v, want %v", err, tt.want)
+			}
+
+			if sent := len(mock.Sent()); (sent == 1) != (tt.want == nil) {
+				t.Errorf("%d messages sent", sent)
+			}
+		})
+	}
+}
+
+func TestNewMockClientWrapsErrors(t *testing.T) {
+	errDenied := awserr.New("AccessDenied", "access denied", nil)
+	tests := []struct {
+		name       string
+		quarantine string
+		operation  string
+		skip       int // the number of calls of operation that succeed before the failing one
+		prefix     string
+	}{
+		{name: "create queue", operation: "CreateQueue", prefix: "sqs: create queue orders: "},
+		{name: "get queue url", operation: "GetQueueUrl", prefix: "sqs: get queue url orders: "},
+		{
+			name:       "get quarantine queue url",
+			quarantine: "orders-quarantine",
+			operation:  "GetQueueUrl",
+			skip:       1,
+			prefix:     "sqs: get queue url orders-quarantine: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			for i := 0; i < tt.skip; i++ {
+				mock.FailNext(tt.operation, nil)
+			}
+			mock.FailNext(tt.operation, errDenied)
+
+			config := testConfig("orders")
+			config.QuarantineQueue = tt.quarantine
+			_, err := NewMockClient(config, mock)
+			if !errors.Is(err, errDenied) {
+				t.Fatalf("NewMockClient() = %v, want it to wrap %v", err, errDenied)
+			}
+			if !strings.HasPrefix(err.Error(), tt.prefix) {
+				t.Errorf("NewMockClient() = %q, want the prefix %q", err, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestDeleteReceiptHandleExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    error
+		handle  string // replaces the receipt handle of the message if set
+		expired bool
+		err     error
+	}{
+		{name: "deleted"},
+		{name: "stale handle", handle: "stale", expired: true},
+		{
+			name:    "expired parameter",
+			fail:    awserr.New("InvalidParameterValue", "Value for parameter ReceiptHandle is invalid. Reason: The receipt handle has expired.", nil),
+			expired: true,
+		},
+		{
+			name: "other parameter",
+			fail: awserr.New("InvalidParameterValue", "Value for parameter QueueUrl is invalid.", nil),
+		},
+		{name: "not in flight", fail: awserr.New(sqs.ErrCodeMessageNotInflight, "message not in flight", nil), expired: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService("body")
+			c := newTestClient(t, testConfig("orders"), mock)
+			msg, err := c.Peek()
+			if err != nil || msg == nil {
+				t.Fatalf("Peek() = %v, %v", msg, err)
+			}
+
+			if tt.handle != "" {
+				msg.ReceiptHandle = &tt.handle
+			}
+			if tt.fail != nil {
+				mock.FailNext("DeleteMessage", tt.fail)
+			}
+
+			err = c.Delete(msg)
+			if errors.Is(err, ErrReceiptHandleExpired) != tt.expired {
+				t.Errorf("Delete() = %v, want expired %v", err, tt.expired)
+			}
+			if (err == nil) != (tt.fail == nil && tt.handle == "") {
+				t.Errorf("Delete() = %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteEmptyReceiptHandle(t *testing.T) {
+	c := newTestClient(t, testConfig("orders"), NewMockAPIService())
+
+	if err := c.Delete(&sqs.Message{}); !errors.Is(err, ErrEmptyReceiptHandle) {
+		t.Errorf("Delete() = %v, want %v", err, ErrEmptyReceiptHandle)
+	}
+}
+
+func TestMinimalReceive(t *testing.T) {
+	for _, minimal := range []bool{false, true} {
+		config := testConfig("orders")
+		config.MinimalReceive = minimal
+		c := newTestClient(t, config, NewMockAPIService())
+		if err := c.InsertWithAttributes("body", map[string]Attribute{"tenant": {DataType: "String", Value: "acme"}}); err != nil {
+			t.Fatalf("InsertWithAttributes: %v", err)
+		}
+
+		msg, err := c.Peek()
+		if err != nil || msg == nil {
+			t.Fatalf("Peek() = %v, %v", msg, err)
+		}
+
+		_, sent := msg.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]
+		_, tenant := msg.MessageAttributes["tenant"]
+		if sent == minimal || tenant == minimal {
+			t.Errorf("MinimalReceive %v: received sent time %v and message attribute %v", minimal, sent, tenant)
+		}
+	}
+}
+
+// payloadSize returns the bytes of the bodies and attributes of msgs, roughly the size of the
+// receive response that carried them.
+func payloadSize(msgs []*sqs.Message) int {
+	n := 0
+	for _, msg := range msgs {
+		n += messageSize(aws.StringValue(msg.Body), msg.MessageAttributes)
+		for name, v := range msg.Attributes {
+			n += len(name) + len(aws.StringValue(v))
+		}
+	}
+
+	return n
+}
+
+func BenchmarkReceive(b *testing.B) {
+	for _, bm := range []struct {
+		name    string
+		minimal bool
+	}{
+		{name: "default"},
+		{name: "minimal", minimal: true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			mock := NewMockAPIService()
+			config := testConfig("orders")
+			// Messages stay visible so that every receive returns a full batch.
+			config.VisibilityTimeoutSeconds = 0
+			config.MinimalReceive = bm.minimal
+			c, err := NewMockClient(config, mock)
+			if err != nil {
+				b.Fatalf("NewMockClient: %v", err)
+			}
+
+			attrs := map[string]Attribute{
+				"trace-id": {DataType: "String", Value: "1-5759e988-bd862e3fe1be46a994272793"},
+				"tenant":   {DataType: "String", Value: "acme"},
+			}
+			for i := 0; i < MaxBatchSize; i++ {
+				if err := c.InsertWithAttributes(`{"order":`+strconv.Itoa(i)+`}`, attrs); err != nil {
+					b.Fatalf("InsertWithAttributes: %v", err)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			payload := 0
+			for i := 0; i < b.N; i++ {
+				msgs, err := c.PeekBatch()
+				if err != nil {
+					b.Fatalf("PeekBatch: %v", err)
+				}
+				payload += payloadSize(msgs)
+			}
+			b.ReportMetric(float64(payload)/float64(b.N), "payload-bytes/op")
+		})
+	}
+}
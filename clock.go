@@ -0,0 +1,25 @@
+package sqs
+
+import "time"
+
+// Clock tells the time for time-dependent behavior such as message age, expiry, receipt handle
+// tracking and receive throttling. Set Config.Clock to control that behavior in tests without
+// sleeping. Waiting, such as polling intervals and backoff, always takes real time: a Clock only
+// decides how long the wait is.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, which reads the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// now returns the current time according to the Client's clock.
+func (c *Client) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+
+	return c.clock.Now()
+}
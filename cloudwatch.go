@@ -0,0 +1,224 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// CloudWatchAPI is the part of the CloudWatch client used to publish metrics, satisfied by
+// *cloudwatch.CloudWatch.
+type CloudWatchAPI interface {
+	PutMetricDataWithContext(aws.Context, *cloudwatch.PutMetricDataInput, ...request.Option) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// defaultCloudWatchNamespace is the namespace metrics are published to if none is configured.
+const defaultCloudWatchNamespace = "arowden-sqs"
+
+// maxMetricData is the number of metrics CloudWatch accepts in a single PutMetricData request.
+const maxMetricData = 20
+
+// cloudWatchErrorMetric counts failures to publish to CloudWatch. It is reported to Config.Metrics
+// rather than to CloudWatch.
+const cloudWatchErrorMetric = "cloudwatch_errors"
+
+// Metrics counted by the Client and published to CloudWatch.
+const (
+	sentMetric     = "messages_sent"
+	receivedMetric = "messages_received"
+	deletedMetric  = "messages_deleted"
+)
+
+// cloudWatchCloseTimeout bounds the final publish made when the Client is closed, so that Close
+// does not hang if CloudWatch is unreachable.
+var cloudWatchCloseTimeout = 10 * time.Second
+
+// errNoCloudWatch is returned by Config.Validate when PublishCloudWatch is set without a client.
+var errNoCloudWatch = errors.New("sqs: PublishCloudWatch requires a CloudWatch client")
+
+// cloudWatchPublisher publishes the queue's approximate depth and the counters reported by the
+// Client to CloudWatch at a regular interval. It is the Client's Metrics while publishing, passing
+// every measurement on to Config.Metrics.
+type cloudWatchPublisher struct {
+	c        *Client
+	next     Metrics
+	api      CloudWatchAPI
+	interval time.Duration
+
+	mu     sync.Mutex
+	counts map[string]int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newCloudWatchPublisher starts publishing the metrics of c to CloudWatch.
+func newCloudWatchPublisher(c *Client) *cloudWatchPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &cloudWatchPublisher{
+		c:        c,
+		next:     c.metrics(),
+		api:      c.config.CloudWatch,
+		interval: c.config.CloudWatchInterval,
+		counts:   make(map[string]int),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	if p.interval <= 0 {
+		p.interval = time.Minute
+	}
+
+	go p.run(ctx)
+	return p
+}
+
+func (p *cloudWatchPublisher) Count(name string, n int) {
+	p.add(name, n)
+	p.next.Count(name, n)
+}
+
+// add adds n to the named counter published to CloudWatch.
+func (p *cloudWatchPublisher) add(name string, n int) {
+	p.mu.Lock()
+	p.counts[name] += n
+	p.mu.Unlock()
+}
+
+func (p *cloudWatchPublisher) Gauge(name string, v float64) {
+	p.next.Gauge(name, v)
+}
+
+func (p *cloudWatchPublisher) Timing(name string, d time.Duration) {
+	p.next.Timing(name, d)
+}
+
+// ForRequest implements RequestMetrics. Counters are published to CloudWatch without the request
+// ID, and passed on with it if Config.Metrics implements RequestMetrics.
+func (p *cloudWatchPublisher) ForRequest(requestID string) Metrics {
+	next, ok := p.next.(RequestMetrics)
+	if !ok {
+		return p
+	}
+
+	return &cloudWatchRequest{p: p, next: next.ForRequest(requestID)}
+}
+
+// cloudWatchRequest is the Metrics of a cloudWatchPublisher for a single request.
+type cloudWatchRequest struct {
+	p    *cloudWatchPublisher
+	next Metrics
+}
+
+func (r *cloudWatchRequest) Count(name string, n int) {
+	r.p.add(name, n)
+	r.next.Count(name, n)
+}
+
+func (r *cloudWatchRequest) Gauge(name string, v float64) {
+	r.next.Gauge(name, v)
+}
+
+func (r *cloudWatchRequest) Timing(name string, d time.Duration) {
+	r.next.Timing(name, d)
+}
+
+// run publishes every interval until ctx is canceled, then publishes once more, within
+// cloudWatchCloseTimeout, so the final counts are not lost.
+func (p *cloudWatchPublisher) run(ctx context.Context) {
+	defer close(p.done)
+
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.publish(ctx)
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), cloudWatchCloseTimeout)
+			p.publish(final)
+			cancel()
+			return
+		}
+	}
+}
+
+// publish sends the queue depth and the counts since the last publish to CloudWatch.
+func (p *cloudWatchPublisher) publish(ctx context.Context) {
+	now := p.c.now()
+	var data []*cloudwatch.MetricDatum
+	if stats, err := p.c.Stats(); err != nil {
+		p.next.Count(cloudWatchErrorMetric, 1)
+	} else {
+		data = append(data,
+			p.datum("Visible", float64(stats.Visible), cloudwatch.StandardUnitCount, now),
+			p.datum("InFlight", float64(stats.InFlight), cloudwatch.StandardUnitCount, now),
+			p.datum("Delayed", float64(stats.Delayed), cloudwatch.StandardUnitCount, now),
+		)
+	}
+
+	p.mu.Lock()
+	counts := p.counts
+	p.counts = make(map[string]int)
+	p.mu.Unlock()
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data = append(data, p.datum(name, float64(counts[name]), cloudwatch.StandardUnitCount, now))
+	}
+
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxMetricData {
+			n = maxMetricData
+		}
+
+		_, err := p.api.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			MetricData: data[:n],
+			Namespace:  aws.String(p.c.cloudWatchNamespace()),
+		})
+		if err != nil {
+			p.next.Count(cloudWatchErrorMetric, 1)
+		}
+
+		data = data[n:]
+	}
+}
+
+// datum returns a CloudWatch metric for the queue.
+func (p *cloudWatchPublisher) datum(name string, v float64, unit string, t time.Time) *cloudwatch.MetricDatum {
+	return &cloudwatch.MetricDatum{
+		Dimensions: []*cloudwatch.Dimension{{
+			Name:  aws.String("QueueName"),
+			Value: aws.String(p.c.config.Name),
+		}},
+		MetricName: aws.String(name),
+		Timestamp:  aws.Time(t),
+		Unit:       aws.String(unit),
+		Value:      aws.Float64(v),
+	}
+}
+
+// close stops publishing after a final publish.
+func (p *cloudWatchPublisher) close() {
+	p.cancel()
+	<-p.done
+}
+
+// cloudWatchNamespace returns the configured CloudWatch namespace or the default.
+func (c *Client) cloudWatchNamespace() string {
+	if c.config.CloudWatchNamespace == "" {
+		return defaultCloudWatchNamespace
+	}
+
+	return c.config.CloudWatchNamespace
+}
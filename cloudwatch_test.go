@@ -0,0 +1,99 @@
+package sqs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// mockCloudWatch is a CloudWatchAPI that records the metrics published to it. If block is set,
+// each request instead waits for its context to be done, like a request to an unreachable
+// endpoint.
+type mockCloudWatch struct {
+	block bool
+
+	mu   sync.Mutex
+	data map[string]float64
+}
+
+func (m *mockCloudWatch) PutMetricDataWithContext(ctx aws.Context, input *cloudwatch.PutMetricDataInput, _ ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	if m.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.data == nil {
+		m.data = make(map[string]float64)
+	}
+	for _, d := range input.MetricData {
+		m.data[aws.StringValue(d.MetricName)] += aws.Float64Value(d.Value)
+	}
+
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+// published returns the total published for the named metric and whether it was published.
+func (m *mockCloudWatch) published(name string) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.data[name]
+	return v, ok
+}
+
+func TestCloudWatchPublishesOnClose(t *testing.T) {
+	api := &mockCloudWatch{}
+	config := testConfig("orders")
+	config.PublishCloudWatch = true
+	config.CloudWatch = api
+	config.CloudWatchInterval = time.Hour
+	c := newTestClient(t, config, NewMockAPIService())
+
+	for _, body := range []string{"a", "b"} {
+		if err := c.Insert(body); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if v, ok := api.published(sentMetric); v != 2 {
+		t.Errorf("%s published as %v (%v), want 2", sentMetric, v, ok)
+	}
+	if v, ok := api.published("Visible"); !ok || v != 2 {
+		t.Errorf("Visible published as %v (%v), want 2", v, ok)
+	}
+}
+
+func TestCloudWatchCloseTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { cloudWatchCloseTimeout = timeout }(cloudWatchCloseTimeout)
+	cloudWatchCloseTimeout = 50 * time.Millisecond
+
+	metrics := &countingMetrics{}
+	config := testConfig("orders")
+	config.Metrics = metrics
+	config.PublishCloudWatch = true
+	config.CloudWatch = &mockCloudWatch{block: true}
+	config.CloudWatchInterval = time.Hour
+	c := newTestClient(t, config, NewMockAPIService())
+
+	start := time.Now()
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %v, want the final publish bounded by the timeout", elapsed)
+	}
+	if n := metrics.count(cloudWatchErrorMetric); n != 1 {
+		t.Errorf("%s = %d, want the timed out publish counted", cloudWatchErrorMetric, n)
+	}
+}
@@ -0,0 +1,95 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Codec converts values to and from message bodies. Set Config.Codec to have InsertValue and
+// PopValue use a common serialization, such as JSON, gob or protobuf, across producers and
+// consumers.
+type Codec interface {
+	Encode(v interface{}) (string, error)
+	Decode(body string, v interface{}) error
+}
+
+// RawCodec is the default Codec. It passes bodies through unchanged: Encode accepts a string or
+// []byte, and Decode accepts a *string or *[]byte.
+type RawCodec struct{}
+
+// Encode returns v, which must be a string or []byte, as a string.
+func (RawCodec) Encode(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+
+	return "", fmt.Errorf("sqs: RawCodec cannot encode %T", v)
+}
+
+// Decode stores body in v, which must be a *string or *[]byte.
+func (RawCodec) Decode(body string, v interface{}) error {
+	switch v := v.(type) {
+	case *string:
+		*v = body
+	case *[]byte:
+		*v = []byte(body)
+	default:
+		return fmt.Errorf("sqs: RawCodec cannot decode into %T", v)
+	}
+
+	return nil
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec struct{}
+
+// Encode returns the JSON encoding of v.
+func (JSONCodec) Encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Decode parses the JSON in body into v.
+func (JSONCodec) Decode(body string, v interface{}) error {
+	return json.Unmarshal([]byte(body), v)
+}
+
+// InsertValue encodes v with the configured Codec and inserts it into the queue as Insert does.
+func (c *Client) InsertValue(v interface{}) error {
+	body, err := c.codec().Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return c.Insert(body)
+}
+
+// PopValue receives a message, decodes its body into v with the configured Codec and deletes it. It
+// reports whether a message was received. A message that cannot be decoded is not deleted, so it is
+// received again after its visibility timeout or moves to the dead-letter queue.
+func (c *Client) PopValue(v interface{}) (bool, error) {
+	msg, err := c.Peek()
+	if err != nil || msg == nil {
+		return false, err
+	}
+
+	if err := c.codec().Decode(aws.StringValue(msg.Body), v); err != nil {
+		return true, err
+	}
+
+	return true, c.Delete(msg)
+}
+
+// codec returns the configured Codec, or RawCodec if none is set.
+func (c *Client) codec() Codec {
+	if c.config.Codec == nil {
+		return RawCodec{}
+	}
+
+	return c.config.Codec
+}
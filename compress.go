@@ -0,0 +1,158 @@
+package sqs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MaxMessageSize is the largest message, in bytes, that SQS will accept. The size of a message is
+// its body plus the names, data types and values of its message attributes.
+const MaxMessageSize = 256 * 1024
+
+// contentEncodingAttribute is the message attribute used to mark a compressed body.
+const contentEncodingAttribute = "Content-Encoding"
+
+const gzipEncoding = "gzip"
+
+// ErrMessageTooLarge is returned when a message, with its attributes, exceeds MaxMessageSize.
+var ErrMessageTooLarge = errors.New("sqs: message exceeds maximum size of 256 KB")
+
+// encodeBody prepares a message body and its attributes for sending. When compression is enabled
+// the body is gzipped and base64 encoded, unless doing so would not make it smaller, and the returned
+// attributes are a copy of attrs that marks the body as compressed.
+func (c *Client) encodeBody(body string, attrs map[string]*sqs.MessageAttributeValue) (string, map[string]*sqs.MessageAttributeValue, error) {
+	if c.config.Compress {
+		compressed, err := compress(body)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(compressed) < len(body) {
+			body = compressed
+			marked := make(map[string]*sqs.MessageAttributeValue, len(attrs)+1)
+			for k, v := range attrs {
+				marked[k] = v
+			}
+
+			marked[contentEncodingAttribute] = &sqs.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(gzipEncoding),
+			}
+			attrs = marked
+		}
+	}
+
+	if messageSize(body, attrs) > MaxMessageSize {
+		return "", nil, ErrMessageTooLarge
+	}
+
+	return body, attrs, nil
+}
+
+// messageSize returns the size of a message as SQS counts it against MaxMessageSize.
+func messageSize(body string, attrs map[string]*sqs.MessageAttributeValue) int {
+	size := len(body)
+	for name, v := range attrs {
+		if v == nil {
+			continue
+		}
+
+		size += len(name) + len(aws.StringValue(v.DataType))
+		size += len(aws.StringValue(v.StringValue)) + len(v.BinaryValue)
+		for _, s := range v.StringListValues {
+			size += len(aws.StringValue(s))
+		}
+		for _, b := range v.BinaryListValues {
+			size += len(b)
+		}
+	}
+
+	return size
+}
+
+// decodeBody replaces the body of a received message with its decompressed contents if it was
+// marked as compressed when sent. The marker attribute is removed so the message can be re-sent as
+// is.
+func decodeBody(msg *sqs.Message) error {
+	encoding, ok := msg.MessageAttributes[contentEncodingAttribute]
+	if !ok || aws.StringValue(encoding.StringValue) != gzipEncoding {
+		return nil
+	}
+
+	body, err := decompress(aws.StringValue(msg.Body))
+	if err != nil {
+		return err
+	}
+
+	msg.Body = &body
+	delete(msg.MessageAttributes, contentEncodingAttribute)
+	return nil
+}
+
+const (
+	// corruptMetric counts received messages marked as compressed whose body could not be
+	// decompressed.
+	corruptMetric = "messages_corrupt"
+	// quarantineErrorMetric counts corrupt messages that could not be moved to the quarantine queue.
+	quarantineErrorMetric = "quarantine_errors"
+)
+
+// rejectCorrupt deals with a received message whose body could not be decompressed, which is left
+// out of the messages returned by the receive so that the rest of the batch is still delivered. It
+// is counted in the "messages_corrupt" metric and moved to the quarantine queue if there is one;
+// otherwise it stays in the queue, to be received again after its visibility timeout and, if the
+// queue has a redrive policy, eventually moved to the dead letter queue.
+func (c *Client) rejectCorrupt(ctx context.Context, msg *sqs.Message) {
+	m := c.metricsFor(ctx)
+	m.Count(corruptMetric, 1)
+	if c.quarantineURL == "" {
+		return
+	}
+
+	if err := c.quarantine(msg); err != nil {
+		m.Count(quarantineErrorMetric, 1)
+	}
+}
+
+// compress gzips s and returns it base64 encoded, since SQS message bodies must be valid text.
+func compress(s string) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompress reverses compress.
+func decompress(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,174 @@
+package sqs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestEncodeBody(t *testing.T) {
+	large := strings.Repeat("compressible ", 1000)
+	trace := map[string]*sqs.MessageAttributeValue{
+		"trace": {DataType: aws.String("String"), StringValue: aws.String("0123456789")},
+	}
+	tests := []struct {
+		name       string
+		compress   bool
+		body       string
+		attrs      map[string]*sqs.MessageAttributeValue
+		compressed bool
+		err        error
+	}{
+		{name: "compression off", body: large},
+		{name: "compressed", compress: true, body: large, compressed: true},
+		{name: "compression does not help", compress: true, body: "hi"},
+		{name: "body at the limit", body: strings.Repeat("a", MaxMessageSize)},
+		{name: "body too large", body: strings.Repeat("a", MaxMessageSize+1), err: ErrMessageTooLarge},
+		{
+			name:  "attributes push it over the limit",
+			body:  strings.Repeat("a", MaxMessageSize-20),
+			attrs: trace,
+			err:   ErrMessageTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{config: Config{Compress: tt.compress}}
+			body, attrs, err := c.encodeBody(tt.body, tt.attrs)
+			if err != tt.err {
+				t.Fatalf("encodeBody() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			_, marked := attrs[contentEncodingAttribute]
+			if marked != tt.compressed {
+				t.Errorf("marked as compressed = %v, want %v", marked, tt.compressed)
+			}
+			if tt.compressed == (body == tt.body) {
+				t.Errorf("body changed = %v, want %v", body != tt.body, tt.compressed)
+			}
+			if _, ok := tt.attrs[contentEncodingAttribute]; ok {
+				t.Error("encodeBody modified the attributes it was passed")
+			}
+		})
+	}
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string // MessageAttributeNames
+	}{
+		{name: "all attributes"},
+		// The compression marker is requested although it is not named.
+		{name: "named attributes", names: []string{"trace"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			config := testConfig("orders")
+			config.Compress = true
+			config.MessageAttributeNames = tt.names
+			c := newTestClient(t, config, mock)
+
+			body := strings.Repeat(`{"item":"widget","quantity":1}`, 200)
+			if err := c.Insert(body); err != nil {
+				t.Fatalf("Insert: %v", err)
+			}
+
+			if stored := mock.Remaining()[0]; stored == body || len(stored) >= len(body) {
+				t.Errorf("stored body was not compressed: %d bytes", len(stored))
+			}
+
+			msg, err := c.Pop()
+			if err != nil {
+				t.Fatalf("Pop: %v", err)
+			}
+			if aws.StringValue(msg.Body) != body {
+				t.Error("Pop did not return the original body")
+			}
+			if _, ok := msg.MessageAttributes[contentEncodingAttribute]; ok {
+				t.Error("Pop left the compression marker on the message")
+			}
+		})
+	}
+}
+
+func TestReceiveSkipsCorruptMessages(t *testing.T) {
+	tests := []struct {
+		name       string
+		quarantine string
+	}{
+		// The corrupt message stays in the queue, in flight, to be retried.
+		{name: "without quarantine"},
+		// The corrupt message is copied to the quarantine queue, which the mock shares with the
+		// source queue, and the original deleted.
+		{name: "with quarantine", quarantine: "orders-quarantine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			metrics := &countingMetrics{}
+			config := testConfig("orders")
+			config.Compress = true
+			config.Metrics = metrics
+			config.QuarantineQueue = tt.quarantine
+			c := newTestClient(t, config, mock)
+
+			for _, input := range []*sqs.SendMessageInput{
+				{MessageBody: aws.String("first")},
+				{
+					MessageBody: aws.String("not gzip"),
+					MessageAttributes: map[string]*sqs.MessageAttributeValue{
+						contentEncodingAttribute: {DataType: aws.String("String"), StringValue: aws.String(gzipEncoding)},
+					},
+				},
+				{MessageBody: aws.String("last")},
+			} {
+				if _, err := mock.SendMessage(input); err != nil {
+					t.Fatalf("SendMessage: %v", err)
+				}
+			}
+
+			msgs, err := c.PeekBatch()
+			if err != nil {
+				t.Fatalf("PeekBatch: %v", err)
+			}
+
+			var bodies []string
+			for _, msg := range msgs {
+				bodies = append(bodies, aws.StringValue(msg.Body))
+			}
+			if strings.Join(bodies, ",") != "first,last" {
+				t.Errorf("PeekBatch returned %q, want the two valid messages", bodies)
+			}
+
+			if n := metrics.count(corruptMetric); n != 1 {
+				t.Errorf("%s = %d, want 1", corruptMetric, n)
+			}
+
+			sent := mock.Sent()
+			quarantined := sent[len(sent)-1] == "not gzip" && len(sent) == 4
+			if quarantined != (tt.quarantine != "") {
+				t.Errorf("quarantined = %v, want %v", quarantined, tt.quarantine != "")
+			}
+
+			var corrupt int
+			for _, body := range mock.Remaining() {
+				if body == "not gzip" {
+					corrupt++
+				}
+			}
+			if corrupt != 1 {
+				t.Errorf("%d copies of the corrupt message remain, want 1", corrupt)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ConsumeOptions configures Consume.
+type ConsumeOptions struct {
+	// ErrorBackoff is how long Consume waits before receiving again after an error. Defaults to 1
+	// second.
+	ErrorBackoff time.Duration
+	// MaxMessages is the number of messages requested by each receive, between 1 and 10. Defaults
+	// to 10 when 0; other values outside the range make Consume fail. Receiving more messages at
+	// once makes fewer requests, but every message received starts its visibility timeout
+	// immediately, so a slow handler may not reach the last messages of a batch before they become
+	// visible again.
+	MaxMessages int
+	// VisibilityTimeoutSeconds overrides Config.VisibilityTimeoutSeconds for messages received by
+	// Consume when greater than 0. Together with MaxMessages it should allow enough time to handle a
+	// whole batch.
+	VisibilityTimeoutSeconds int
+	// HonorProcessAfter makes Consume hold back messages inserted with InsertAt until their process
+	// after time, by re-inserting them with a new delay instead of delivering them.
+	HonorProcessAfter bool
+	// OnOutOfOrder, if set, is called when Consume receives a message that was sent before the
+	// message received just before it, with the previous message's sent time. It only observes
+	// delivery order, which standard queues do not guarantee, and is useful for measuring how often
+	// messages are reordered.
+	OnOutOfOrder func(msg *sqs.Message, previous time.Time)
+	// MaxProcessingAttempts, if greater than 0, stops Consume delivering a message once it has been
+	// received more than this many times, as a safety net for poison messages on queues without a
+	// dead-letter queue. Such messages are passed to OnPoisonMessage instead.
+	MaxProcessingAttempts int
+	// OnPoisonMessage is called with messages that exceed MaxProcessingAttempts, and could for
+	// example move them to another queue with MoveTo. If it returns nil, or is not set, the message
+	// is deleted; otherwise the error is sent on Consume's error channel and the message stays in the
+	// queue.
+	OnPoisonMessage func(msg *sqs.Message) error
+	// SmartReceive makes each receive start with a short poll, which returns immediately, and only
+	// fall back to a long poll if the short poll finds no messages. Long polls return as soon as any
+	// message arrives, so on a busy queue this mainly saves the time a long poll spends gathering a
+	// full batch. An idle queue costs an extra request for every long poll, up to twice as many
+	// requests as plain long polling, and a short poll samples only some SQS servers, so it can miss
+	// messages that the following long poll then finds.
+	SmartReceive bool
+	// DropExpired makes Consume delete messages inserted with InsertWithExpiry that are received
+	// after their expiry time instead of delivering them, counting each in the "messages_expired"
+	// metric. Expiry is enforced here, by the consumer, so an expired message stays in the queue
+	// until it is received.
+	DropExpired bool
+	// SupportedSchemaVersions, if not empty, stops Consume delivering messages whose schema version,
+	// as set by Config.SchemaVersion, is not in the list. Messages without a version are treated as
+	// having the version "". Unsupported messages are moved to the quarantine queue if
+	// Config.QuarantineQueue is set, and a *QuarantineError is sent on the error channel; otherwise
+	// they are left in the queue, to be retried or moved to the dead-letter queue, and an error
+	// wrapping ErrUnsupportedSchema is sent.
+	SupportedSchemaVersions []string
+	// DuplicateWindow, if greater than 0, makes Consume remember the IDs of the last DuplicateWindow
+	// messages it delivered and hold back a message that arrives again while its earlier delivery
+	// should still be invisible, that is within the visibility timeout of that delivery. Standard
+	// queues deliver at least once, so this is a best-effort filter, not exactly-once delivery: a
+	// duplicate that arrives after the timeout, or after its ID has been evicted, is delivered
+	// again, as are messages whose handling failed. Each remembered ID costs roughly 200 bytes, and
+	// the window should cover the messages delivered in one visibility timeout. Duplicates are
+	// counted in the "messages_duplicate" metric.
+	DuplicateWindow int
+	// OnDuplicate, if set, is called with each duplicate held back because of DuplicateWindow.
+	// Duplicates are not deleted, because the earlier delivery may still fail, but SQS only accepts
+	// the receipt handle of the latest delivery, so the earlier delivery's Delete may leave the
+	// message in the queue. OnDuplicate can delete the duplicate instead if that is preferable.
+	OnDuplicate func(msg *sqs.Message)
+	// Deleter, if set, deletes messages in batches instead of one request per message: the messages
+	// Consume deletes itself, such as expired and poison messages, and in Process the messages
+	// handled successfully. It is not closed when Consume or Process returns; close it afterwards to
+	// delete the messages still pending.
+	Deleter *BatchDeleter
+}
+
+// Consume receives messages from the queue until ctx is canceled and sends them on the returned
+// message channel. Messages are not deleted; call Delete once each has been processed. Errors from
+// receiving are sent on the returned error channel and do not stop Consume, so the caller decides
+// whether to cancel ctx. Both channels must be drained and are closed once Consume has stopped. If
+// opts is invalid, the error is sent on the error channel and Consume stops immediately.
+func (c *Client) Consume(ctx context.Context, opts ConsumeOptions) (<-chan *sqs.Message, <-chan error) {
+	if opts.ErrorBackoff <= 0 {
+		opts.ErrorBackoff = time.Second
+	}
+
+	maxMessages := opts.MaxMessages
+	if maxMessages == 0 {
+		maxMessages = MaxBatchSize
+	}
+
+	input := c.receiveInput(maxMessages)
+	if opts.VisibilityTimeoutSeconds > 0 {
+		input.VisibilityTimeout = aws.Int64(int64(opts.VisibilityTimeoutSeconds))
+	}
+
+	msgs := make(chan *sqs.Message)
+	errs := make(chan error)
+	go func() {
+		defer close(msgs)
+		defer close(errs)
+
+		if maxMessages < 1 || maxMessages > MaxBatchSize {
+			send(ctx, errs, fmt.Errorf("sqs: MaxMessages %d must be between 1 and %d", maxMessages, MaxBatchSize))
+			return
+		}
+
+		if err := validateVisibilityTimeout(opts.VisibilityTimeoutSeconds); err != nil {
+			send(ctx, errs, err)
+			return
+		}
+
+		var recent *recentDeliveries
+		if opts.DuplicateWindow > 0 {
+			recent = newRecentDeliveries(opts.DuplicateWindow)
+		}
+		timeout := time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second
+
+		var lastSent time.Time
+		for ctx.Err() == nil {
+			resp, err := c.consumeReceive(ctx, input, opts.SmartReceive)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if !send(ctx, errs, err) || !sleep(ctx, opts.ErrorBackoff) {
+					return
+				}
+				continue
+			}
+
+			for _, msg := range resp.Messages {
+				ok, err := c.admit(msg, opts)
+				if err != nil && !send(ctx, errs, err) {
+					return
+				}
+
+				if !ok {
+					continue
+				}
+
+				if recent != nil && recent.duplicate(msg, c.now(), timeout) {
+					c.metrics().Count(duplicateMetric, 1)
+					if opts.OnDuplicate != nil {
+						opts.OnDuplicate(msg)
+					}
+					continue
+				}
+
+				if opts.OnOutOfOrder != nil {
+					lastSent = checkOrder(msg, lastSent, opts.OnOutOfOrder)
+				}
+
+				select {
+				case msgs <- msg:
+					c.retain([]*sqs.Message{msg})
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return msgs, errs
+}
+
+// consumeReceive receives messages for Consume, first with a short poll if smart is set.
+func (c *Client) consumeReceive(ctx context.Context, input *sqs.ReceiveMessageInput, smart bool) (*sqs.ReceiveMessageOutput, error) {
+	if smart {
+		short := *input
+		short.WaitTimeSeconds = aws.Int64(0)
+		resp, err := c.receive(ctx, &short)
+		if err != nil || len(resp.Messages) > 0 {
+			return resp, err
+		}
+	}
+
+	return c.receive(ctx, input)
+}
+
+// admit reports whether a received message should be delivered by Consume. Messages that are not
+// delivered have already been dealt with, for example quarantined or requeued.
+func (c *Client) admit(msg *sqs.Message, opts ConsumeOptions) (bool, error) {
+	if err := c.validate(msg); err != nil {
+		return false, err
+	}
+
+	if len(opts.SupportedSchemaVersions) > 0 {
+		if err := c.checkSchemaVersion(msg, opts.SupportedSchemaVersions); err != nil {
+			return false, err
+		}
+	}
+
+	if opts.DropExpired && isExpired(msg, c.now()) {
+		if err := c.deleteWith(opts.Deleter, msg); err != nil {
+			return false, err
+		}
+
+		c.metrics().Count(expiredMetric, 1)
+		return false, nil
+	}
+
+	if opts.MaxProcessingAttempts > 0 {
+		if n, ok := ReceiveCount(msg); ok && n > opts.MaxProcessingAttempts {
+			return false, c.dropPoison(msg, opts.OnPoisonMessage, opts.Deleter)
+		}
+	}
+
+	if opts.HonorProcessAfter {
+		if wait := processAfterDelay(msg, c.now()); wait > 0 {
+			return false, c.requeue(msg, wait)
+		}
+	}
+
+	return true, nil
+}
+
+// ErrUnsupportedSchema is the error, wrapped, for a message whose schema version a consumer does not
+// support.
+var ErrUnsupportedSchema = errors.New("sqs: unsupported schema version")
+
+// checkSchemaVersion returns an error if the schema version of msg is not one of supported, moving
+// the message to the quarantine queue if there is one.
+func (c *Client) checkSchemaVersion(msg *sqs.Message, supported []string) error {
+	version, _ := SchemaVersion(msg)
+	for _, v := range supported {
+		if v == version {
+			return nil
+		}
+	}
+
+	err := fmt.Errorf("%w %q", ErrUnsupportedSchema, version)
+	if c.quarantineURL == "" {
+		return err
+	}
+
+	if qerr := c.quarantine(msg); qerr != nil {
+		return qerr
+	}
+
+	return &QuarantineError{MessageID: aws.StringValue(msg.MessageId), Err: err}
+}
+
+// dropPoison passes a message that has been received too many times to onPoison, if set, and
+// deletes it, through d if it is set, unless onPoison fails.
+func (c *Client) dropPoison(msg *sqs.Message, onPoison func(*sqs.Message) error, d *BatchDeleter) error {
+	if onPoison != nil {
+		if err := onPoison(msg); err != nil {
+			return err
+		}
+	}
+
+	return c.deleteWith(d, msg)
+}
+
+// checkOrder calls onOutOfOrder if msg was sent before last, the sent time of the previous message,
+// and returns the sent time to compare the next message with.
+func checkOrder(msg *sqs.Message, last time.Time, onOutOfOrder func(*sqs.Message, time.Time)) time.Time {
+	sent, err := SentTime(msg)
+	if err != nil {
+		return last
+	}
+
+	if sent.Before(last) {
+		onOutOfOrder(msg, last)
+	}
+
+	return sent
+}
+
+// send sends err on errs, returning false if ctx is canceled first.
+func send(ctx context.Context, errs chan<- error, err error) bool {
+	select {
+	case errs <- err:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sleep waits for d, returning false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// emptied returns a channel that is closed once mock holds no messages, or ctx is done.
+func emptied(ctx context.Context, mock *MockAPIService) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for len(mock.Remaining()) > 0 && sleep(ctx, time.Millisecond) {
+		}
+	}()
+
+	return done
+}
+
+func TestConsumeMaxProcessingAttempts(t *testing.T) {
+	errKeep := errors.New("keep it")
+	tests := []struct {
+		name      string
+		receives  int // the number of times the message is received before Consume
+		onPoison  error
+		delivered bool
+		deleted   bool
+		err       error
+	}{
+		{name: "first attempt", delivered: true},
+		{name: "last attempt", receives: 1, delivered: true},
+		{name: "poison", receives: 2, deleted: true},
+		{name: "poison kept", receives: 2, onPoison: errKeep, err: errKeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService("body")
+			config := testConfig("orders")
+			// Messages become visible again as soon as they are received, so each Peek counts as
+			// a processing attempt.
+			config.VisibilityTimeoutSeconds = 0
+			c := newTestClient(t, config, mock)
+			for i := 0; i < tt.receives; i++ {
+				if _, err := c.Peek(); err != nil {
+					t.Fatalf("Peek: %v", err)
+				}
+			}
+
+			var poisoned *sqs.Message
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			msgs, errs := c.Consume(ctx, ConsumeOptions{
+				MaxMessages:           1,
+				MaxProcessingAttempts: 2,
+				// Hide the message once Consume receives it, so it is received once more at most.
+				VisibilityTimeoutSeconds: 30,
+				OnPoisonMessage: func(msg *sqs.Message) error {
+					poisoned = msg
+					return tt.onPoison
+				},
+			})
+
+			var delivered bool
+			var err error
+			select {
+			case msg := <-msgs:
+				delivered = aws.StringValue(msg.Body) == "body"
+			case err = <-errs:
+			case <-emptied(ctx, mock):
+			}
+			cancel()
+			for range msgs {
+			}
+			for range errs {
+			}
+
+			if delivered != tt.delivered {
+				t.Errorf("delivered = %v, want %v", delivered, tt.delivered)
+			}
+			if err != tt.err {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if (poisoned != nil) != (tt.receives >= 2) {
+				t.Errorf("OnPoisonMessage called = %v, want %v", poisoned != nil, tt.receives >= 2)
+			}
+			if deleted := len(mock.Remaining()) == 0; deleted != tt.deleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
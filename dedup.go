@@ -0,0 +1,49 @@
+package sqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrMissingDeduplicationID is returned when an empty deduplication ID is given.
+var ErrMissingDeduplicationID = errors.New("sqs: deduplication ID is required")
+
+// DeduplicationID returns the deduplication ID used for body when Config.HashDeduplicationID is
+// set: the hex-encoded SHA-256 of the body.
+func DeduplicationID(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// InsertWithDeduplicationID is like InsertWithGroup but sets the message's deduplication ID, so any
+// other message sent with the same ID within the 5 minute deduplication window is discarded. It
+// overrides Config.HashDeduplicationID and the queue's content-based deduplication.
+func (c *Client) InsertWithDeduplicationID(input, groupID, dedupID string) error {
+	if groupID == "" {
+		return ErrMissingGroupID
+	}
+
+	if dedupID == "" {
+		return ErrMissingDeduplicationID
+	}
+
+	return c.sendMessage(&sqs.SendMessageInput{
+		MessageBody:            &input,
+		MessageDeduplicationId: &dedupID,
+		MessageGroupId:         &groupID,
+		QueueUrl:               c.currentURL(),
+	})
+}
+
+// deduplicationID returns the deduplication ID to send body with, or nil to leave it to the queue.
+func (c *Client) deduplicationID(body string) *string {
+	if !c.config.HashDeduplicationID || !c.config.isFIFO() {
+		return nil
+	}
+
+	id := DeduplicationID(body)
+	return &id
+}
@@ -0,0 +1,91 @@
+package sqs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// recordingMock is a MockAPIService that records the SendMessage requests it is sent.
+type recordingMock struct {
+	*MockAPIService
+	sent []*sqs.SendMessageInput
+}
+
+func (m *recordingMock) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	m.sent = append(m.sent, input)
+	return m.MockAPIService.SendMessageWithContext(ctx, input, opts...)
+}
+
+func TestDeduplicationID(t *testing.T) {
+	a, b := DeduplicationID("order 1"), DeduplicationID("order 2")
+	if a != DeduplicationID("order 1") {
+		t.Error("identical bodies have different deduplication IDs")
+	}
+	if a == b {
+		t.Error("different bodies have the same deduplication ID")
+	}
+	if len(a) != 64 {
+		t.Errorf("deduplication ID %q is %d characters, want a hex SHA-256 of 64", a, len(a))
+	}
+}
+
+func TestHashDeduplicationID(t *testing.T) {
+	tests := []struct {
+		name   string
+		queue  string
+		hash   bool
+		insert func(c *Client) error
+		want   string
+	}{
+		{
+			name:   "hashed",
+			queue:  "orders.fifo",
+			hash:   true,
+			insert: func(c *Client) error { return c.InsertWithGroup("order 1", "orders") },
+			want:   DeduplicationID("order 1"),
+		},
+		{
+			name:   "not enabled",
+			queue:  "orders.fifo",
+			insert: func(c *Client) error { return c.InsertWithGroup("order 1", "orders") },
+		},
+		{
+			name:   "standard queue",
+			queue:  "orders",
+			hash:   true,
+			insert: func(c *Client) error { return c.Insert("order 1") },
+		},
+		{
+			name:   "overridden",
+			queue:  "orders.fifo",
+			hash:   true,
+			insert: func(c *Client) error { return c.InsertWithDeduplicationID("order 1", "orders", "order-1") },
+			want:   "order-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &recordingMock{MockAPIService: NewMockAPIService()}
+			config := testConfig(tt.queue)
+			config.HashDeduplicationID = tt.hash
+			c, err := newClient(config, mock)
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			if err := tt.insert(c); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+			if len(mock.sent) != 1 {
+				t.Fatalf("%d messages sent, want 1", len(mock.sent))
+			}
+			if got := aws.StringValue(mock.sent[0].MessageDeduplicationId); got != tt.want {
+				t.Errorf("MessageDeduplicationId = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// BatchDeleter collects processed messages and deletes them from the queue in batches, which uses
+// far fewer requests than deleting each message with Delete. It is intended for consumers using
+// Consume, and Consume and Process use it themselves when it is set as ConsumeOptions.Deleter. A
+// batch is deleted when it holds 10 messages or when the flush interval elapses. Messages that fail
+// to delete are kept and retried on the next flush, unless AWS rejects them for a reason retrying
+// would not fix, in which case they are dropped and reported in a *DeleteError.
+type BatchDeleter struct {
+	client *Client
+
+	mu      sync.Mutex
+	pending []*sqs.Message
+	err     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// DeleteError is returned by a BatchDeleter that gave up deleting some messages because AWS rejected
+// them, or the requests deleting them, with errors that retrying would not fix. The messages remain
+// in the queue and will be received again once their visibility timeouts expire.
+type DeleteError struct {
+	Messages []*sqs.Message
+	Err      error
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("sqs: dropped %d pending deletes: %v", len(e.Messages), e.Err)
+}
+
+func (e *DeleteError) Unwrap() error {
+	return e.Err
+}
+
+// NewBatchDeleter returns a BatchDeleter for the queue. Pending deletes are flushed in the
+// background every flushInterval; it should be well below the visibility timeout, since a message
+// whose timeout expires before it is deleted will be received again. Errors from background flushes
+// are returned by the next call to Delete, Flush or Close. If flushInterval is not positive it
+// defaults to 1 second.
+func (c *Client) NewBatchDeleter(flushInterval time.Duration) *BatchDeleter {
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	d := &BatchDeleter{
+		client: c,
+		done:   make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.flushEvery(flushInterval)
+	return d
+}
+
+// Delete queues msg to be deleted, flushing if a full batch is pending. A message without a receipt
+// handle is rejected, and a message already pending is not queued again. If an error from a
+// background flush is returned, msg is not queued and Delete should be retried.
+func (d *BatchDeleter) Delete(msg *sqs.Message) error {
+	if err := validateReceiptHandle(msg); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.takeErr(); err != nil {
+		return err
+	}
+
+	for _, p := range d.pending {
+		if aws.StringValue(p.ReceiptHandle) == aws.StringValue(msg.ReceiptHandle) {
+			return nil
+		}
+	}
+
+	d.pending = append(d.pending, msg)
+	if len(d.pending) < MaxBatchSize {
+		return nil
+	}
+
+	return d.flush()
+}
+
+// Flush deletes all pending messages. Messages that could not be deleted remain pending, unless
+// they were rejected, in which case they are dropped and returned in a *DeleteError.
+func (d *BatchDeleter) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.takeErr(); err != nil {
+		return err
+	}
+
+	return d.flush()
+}
+
+// Close stops background flushing and deletes any pending messages. It is safe to call more than
+// once, so that a Close that failed to delete everything can be retried.
+func (d *BatchDeleter) Close() error {
+	d.closeOnce.Do(func() { close(d.done) })
+	d.wg.Wait()
+	return d.Flush()
+}
+
+// flushEvery flushes pending deletes every interval until the deleter is closed.
+func (d *BatchDeleter) flushEvery(interval time.Duration) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			if err := d.flush(); err != nil && d.err == nil {
+				d.err = err
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// flush deletes the pending messages in batches of 10. Batches that fail with an error that may
+// not recur stop the flush and remain pending; rejected batches and entries are dropped. d.mu must
+// be held.
+func (d *BatchDeleter) flush() error {
+	var retry, dropped []*sqs.Message
+	var expired int
+	var err, dropErr error
+	for len(d.pending) > 0 {
+		n := len(d.pending)
+		if n > MaxBatchSize {
+			n = MaxBatchSize
+		}
+
+		batch := d.pending[:n]
+		failed, rejected, gone, batchErr := d.client.deleteBatch(context.Background(), batch)
+		if batchErr != nil && isRetryableSend(batchErr) {
+			err = batchErr
+			break
+		}
+
+		d.pending = d.pending[n:]
+		if batchErr != nil {
+			dropped = append(dropped, batch...)
+			dropErr = batchErr
+			continue
+		}
+
+		retry = append(retry, failed...)
+		if len(rejected) > 0 {
+			dropped = append(dropped, rejected...)
+			dropErr = fmt.Errorf("sqs: AWS rejected %d deletes", len(rejected))
+		}
+		expired += len(gone)
+	}
+
+	d.pending = append(retry, d.pending...)
+	if len(dropped) > 0 {
+		return &DeleteError{Messages: dropped, Err: dropErr}
+	}
+
+	if err == nil && len(retry) > 0 {
+		err = fmt.Errorf("sqs: failed to delete %d messages", len(retry))
+	}
+
+	if err == nil && expired > 0 {
+		err = expiredError(expired)
+	}
+
+	return err
+}
+
+// deleteWith deletes msg through d if it is set, and otherwise immediately.
+func (c *Client) deleteWith(d *BatchDeleter, msg *sqs.Message) error {
+	if d != nil {
+		return d.Delete(msg)
+	}
+
+	return c.Delete(msg)
+}
+
+// takeErr returns and clears the error from the last background flush. d.mu must be held.
+func (d *BatchDeleter) takeErr() error {
+	err := d.err
+	d.err = nil
+	return err
+}
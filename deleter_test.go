@@ -0,0 +1,173 @@
+package sqs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// rejectingDeleteMock is a MockAPIService that rejects the delete entries of messages whose receipt
+// handle is in reject as invalid, a failure that retrying would not fix.
+type rejectingDeleteMock struct {
+	*MockAPIService
+	reject map[string]bool
+}
+
+func (m rejectingDeleteMock) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	accepted := *input
+	accepted.Entries = nil
+	var failed []*sqs.BatchResultErrorEntry
+	for _, e := range input.Entries {
+		if !m.reject[aws.StringValue(e.ReceiptHandle)] {
+			accepted.Entries = append(accepted.Entries, e)
+			continue
+		}
+
+		failed = append(failed, &sqs.BatchResultErrorEntry{
+			Code:        aws.String("InvalidParameterValue"),
+			Id:          e.Id,
+			Message:     aws.String("invalid"),
+			SenderFault: aws.Bool(true),
+		})
+	}
+
+	out, err := m.MockAPIService.DeleteMessageBatchWithContext(ctx, &accepted, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out.Failed = append(out.Failed, failed...)
+
+	return out, nil
+}
+
+// receivedBodies returns the bodies of msgs.
+func receivedBodies(msgs []*sqs.Message) []string {
+	var bodies []string
+	for _, msg := range msgs {
+		bodies = append(bodies, aws.StringValue(msg.Body))
+	}
+
+	return bodies
+}
+
+func TestBatchDeleterFlush(t *testing.T) {
+	errThrottled := awserr.New("ThrottlingException", "Rate exceeded", nil)
+	errDenied := awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), 403, "id")
+	tests := []struct {
+		name      string
+		fail      error  // the error of the next DeleteMessageBatch request
+		reject    string // the body of a message whose delete entry is rejected
+		remaining []string
+		dropped   []string
+		pending   int
+	}{
+		{name: "deleted"},
+		{name: "throttled request", fail: errThrottled, remaining: []string{"a", "b", "c"}, pending: 3},
+		{name: "rejected request", fail: errDenied, remaining: []string{"a", "b", "c"}, dropped: []string{"a", "b", "c"}},
+		{name: "rejected entry", reject: "b", remaining: []string{"b"}, dropped: []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService("a", "b", "c")
+			wrapper := rejectingDeleteMock{MockAPIService: mock, reject: map[string]bool{}}
+			c, err := newClient(testConfig("orders"), wrapper)
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			msgs, err := c.PeekBatch()
+			if err != nil {
+				t.Fatalf("PeekBatch: %v", err)
+			}
+
+			d := c.NewBatchDeleter(time.Hour)
+			for _, msg := range msgs {
+				if aws.StringValue(msg.Body) == tt.reject {
+					wrapper.reject[aws.StringValue(msg.ReceiptHandle)] = true
+				}
+				if err := d.Delete(msg); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			}
+
+			if tt.fail != nil {
+				mock.FailNext("DeleteMessageBatch", tt.fail)
+			}
+			err = d.Flush()
+
+			var dropped *DeleteError
+			if errors.As(err, &dropped) {
+				if got := receivedBodies(dropped.Messages); !reflect.DeepEqual(got, tt.dropped) {
+					t.Errorf("dropped %q, want %q", got, tt.dropped)
+				}
+			} else if tt.dropped != nil {
+				t.Errorf("Flush() = %v, want a *DeleteError", err)
+			}
+			if (err != nil) != (tt.dropped != nil || tt.pending > 0) {
+				t.Errorf("Flush() = %v", err)
+			}
+
+			if got := mock.Remaining(); len(got) != len(tt.remaining) || len(got) > 0 && !reflect.DeepEqual(got, tt.remaining) {
+				t.Errorf("Remaining() = %q, want %q", got, tt.remaining)
+			}
+			if len(d.pending) != tt.pending {
+				t.Errorf("%d deletes pending, want %d", len(d.pending), tt.pending)
+			}
+
+			// Dropped messages no longer hold up later flushes.
+			if err := d.Close(); err != nil {
+				t.Errorf("Close() = %v", err)
+			}
+		})
+	}
+}
+
+func TestBatchDeleterDelete(t *testing.T) {
+	mock := NewMockAPIService("a", "b")
+	c := newTestClient(t, testConfig("orders"), mock)
+	msgs, err := c.PeekBatch()
+	if err != nil {
+		t.Fatalf("PeekBatch: %v", err)
+	}
+
+	d := c.NewBatchDeleter(time.Hour)
+	if err := d.Delete(&sqs.Message{MessageId: aws.String("id")}); !errors.Is(err, ErrEmptyReceiptHandle) {
+		t.Errorf("Delete() of a message without a receipt handle = %v, want %v", err, ErrEmptyReceiptHandle)
+	}
+
+	// An error from a background flush is returned without queuing the message.
+	errFlush := errors.New("flush failed")
+	d.err = errFlush
+	if err := d.Delete(msgs[0]); err != errFlush {
+		t.Errorf("Delete() = %v, want the background flush error", err)
+	}
+	if len(d.pending) != 0 {
+		t.Errorf("%d deletes pending, want the message not queued with the error", len(d.pending))
+	}
+
+	for _, msg := range []*sqs.Message{msgs[0], msgs[0], msgs[1]} {
+		if err := d.Delete(msg); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	if len(d.pending) != 2 {
+		t.Errorf("%d deletes pending, want the duplicate queued once", len(d.pending))
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if n := len(mock.Remaining()); n != 0 {
+		t.Errorf("%d messages remain, want both deleted", n)
+	}
+}
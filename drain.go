@@ -0,0 +1,68 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DrainAll receives and deletes messages until the queue is empty, maxMessages messages have been
+// drained, or ctx is done, and returns the messages drained. A maxMessages of 0 means no limit; use
+// a context deadline to bound how long DrainAll runs on a queue that is still being written to;
+// reaching either limit is not an error. If an error occurs, the messages drained so far are
+// returned with it. A message is only returned once it has been deleted.
+func (c *Client) DrainAll(ctx context.Context, maxMessages int) ([]*sqs.Message, error) {
+	var drained []*sqs.Message
+	for maxMessages <= 0 || len(drained) < maxMessages {
+		if ctx.Err() != nil {
+			return drained, nil
+		}
+
+		n := MaxBatchSize
+		if maxMessages > 0 && maxMessages-len(drained) < n {
+			n = maxMessages - len(drained)
+		}
+
+		resp, err := c.receiveNitemsContext(ctx, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return drained, nil
+			}
+			return drained, err
+		}
+
+		if len(resp.Messages) == 0 {
+			return drained, nil
+		}
+
+		failed, rejected, expired, err := c.deleteBatch(ctx, resp.Messages)
+		if err != nil {
+			return drained, err
+		}
+
+		drained = append(drained, deletedMessages(resp.Messages, append(append(failed, rejected...), expired...))...)
+	}
+
+	return drained, nil
+}
+
+// deletedMessages returns the messages of msgs that are not in failed.
+func deletedMessages(msgs, failed []*sqs.Message) []*sqs.Message {
+	if len(failed) == 0 {
+		return msgs
+	}
+
+	skip := make(map[*sqs.Message]bool, len(failed))
+	for _, msg := range failed {
+		skip[msg] = true
+	}
+
+	var deleted []*sqs.Message
+	for _, msg := range msgs {
+		if !skip[msg] {
+			deleted = append(deleted, msg)
+		}
+	}
+
+	return deleted
+}
@@ -0,0 +1,61 @@
+package sqs
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// duplicateMetric counts deliveries Consume detected as duplicates.
+const duplicateMetric = "messages_duplicate"
+
+// recentDelivery is a message delivered by Consume and tracked by recentDeliveries.
+type recentDelivery struct {
+	id string
+	// invisibleUntil is when the visibility timeout of the delivery ends.
+	invisibleUntil time.Time
+}
+
+// recentDeliveries remembers the most recent messages delivered by Consume, evicting the least
+// recently delivered once it holds size messages. It is only used by the goroutine of one Consume
+// call, so it needs no lock.
+type recentDeliveries struct {
+	size  int
+	order *list.List
+	byID  map[string]*list.Element
+}
+
+func newRecentDeliveries(size int) *recentDeliveries {
+	return &recentDeliveries{
+		size:  size,
+		order: list.New(),
+		byID:  make(map[string]*list.Element, size),
+	}
+}
+
+// duplicate reports whether msg arrived at now while an earlier delivery of it should still have
+// been invisible, and otherwise records that it was delivered at now with the visibility timeout.
+func (r *recentDeliveries) duplicate(msg *sqs.Message, now time.Time, timeout time.Duration) bool {
+	id := aws.StringValue(msg.MessageId)
+	if e, ok := r.byID[id]; ok {
+		d := e.Value.(*recentDelivery)
+		if now.Before(d.invisibleUntil) {
+			return true
+		}
+
+		d.invisibleUntil = now.Add(timeout)
+		r.order.MoveToFront(e)
+		return false
+	}
+
+	r.byID[id] = r.order.PushFront(&recentDelivery{id: id, invisibleUntil: now.Add(timeout)})
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.byID, oldest.Value.(*recentDelivery).id)
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// fipsRegions are the commercial regions with FIPS SQS endpoints. The GovCloud regions only have
+// FIPS endpoints, so their standard endpoints are used.
+var fipsRegions = map[string]bool{
+	"us-east-1": true,
+	"us-east-2": true,
+	"us-west-1": true,
+	"us-west-2": true,
+}
+
+// validateEndpoint returns an error if the FIPS or dualstack endpoint options cannot be used in the
+// configured region.
+func (c Config) validateEndpoint() error {
+	if !c.UseFIPSEndpoint && !c.UseDualStack {
+		return nil
+	}
+
+	if c.Region == "" {
+		return errors.New("sqs: UseFIPSEndpoint and UseDualStack require a Region")
+	}
+
+	if c.UseFIPSEndpoint && !fipsRegions[c.Region] && !isGovCloud(c.Region) {
+		return fmt.Errorf("sqs: region %s has no FIPS endpoint", c.Region)
+	}
+
+	if c.UseDualStack && strings.HasPrefix(c.Region, "cn-") {
+		return fmt.Errorf("sqs: region %s has no dualstack endpoint", c.Region)
+	}
+
+	return nil
+}
+
+// sqsConfig returns the configuration for the SQS client, which selects the FIPS or dualstack
+// endpoint if requested. The endpoint is set on the SQS client only, not on the session, which is
+// shared with other services. The AWS SDK version this package uses cannot resolve these endpoints
+// itself.
+func (c Config) sqsConfig() *aws.Config {
+	host := "sqs"
+	if c.UseFIPSEndpoint && !isGovCloud(c.Region) {
+		host = "sqs-fips"
+	}
+
+	domain := "amazonaws.com"
+	if c.UseDualStack {
+		domain = "api.aws"
+	}
+
+	if host == "sqs" && domain == "amazonaws.com" {
+		return &aws.Config{}
+	}
+
+	return &aws.Config{Endpoint: aws.String(fmt.Sprintf("https://%s.%s.%s", host, c.Region, domain))}
+}
+
+// isGovCloud reports whether region is an AWS GovCloud region.
+func isGovCloud(region string) bool {
+	return strings.HasPrefix(region, "us-gov-")
+}
@@ -1,6 +1,10 @@
 module github.com/arowden/sqs
 
+go 1.18
+
 require (
 	github.com/aws/aws-sdk-go v1.19.1
 	github.com/smartystreets/goconvey v0.0.0-20190306220146-200a235640ff
 )
+
+require github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af // indirect
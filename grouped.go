@@ -0,0 +1,121 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrGroupBlocked is the result of an item of InsertBatchGrouped that was not sent because an
+// earlier item in the same message group failed, so sending it would have broken the group's order.
+var ErrGroupBlocked = errors.New("sqs: not sent because an earlier message in its group failed")
+
+// ErrNotFIFO is returned by operations that require a FIFO queue.
+var ErrNotFIFO = errors.New("sqs: operation requires a FIFO queue")
+
+// ErrFIFOUnsupported is returned by operations that are not supported on FIFO queues, such as
+// InsertAt and InsertWithExpiry.
+var ErrFIFOUnsupported = errors.New("sqs: operation is not supported on FIFO queues")
+
+// GroupedItem is a message body for InsertBatchGrouped along with its message group.
+type GroupedItem struct {
+	Body    string
+	GroupID string
+}
+
+// InsertBatchGrouped inserts any number of items into a FIFO queue in batches, each into its own
+// message group, so a producer can spread messages across groups by a key of its choosing. Items
+// are sent in order and a batch is only sent once the previous one has completed, so each group
+// receives its items in the order given. If an item fails, items of the same group in later batches
+// are not sent and have the result ErrGroupBlocked, so later sends cannot reorder the group; items in
+// other groups are still sent. Other items of the group in the same batch were already sent, and
+// their results report what SQS did with them. The results are in the same order as items and the
+// returned error is the first item error, if any. Nothing is sent if the queue is not FIFO or any
+// item has no group ID, in which case ErrNotFIFO or ErrMissingGroupID is returned.
+func (c *Client) InsertBatchGrouped(items []GroupedItem) ([]BatchResult, error) {
+	if !c.config.isFIFO() {
+		return nil, ErrNotFIFO
+	}
+
+	for _, item := range items {
+		if item.GroupID == "" {
+			return nil, ErrMissingGroupID
+		}
+	}
+
+	results := make([]BatchResult, len(items))
+	blocked := make(map[string]bool)
+	var pending []int
+	send := func() {
+		bodies := make([]string, len(pending))
+		for j, i := range pending {
+			bodies[j] = items[i].Body
+		}
+
+		n := nextBatchLen(bodies)
+		c.sendGroupedBatch(items, pending[:n], results, blocked)
+		pending = pending[n:]
+	}
+
+	for i := range items {
+		pending = append(pending, i)
+		if len(pending) == MaxBatchSize {
+			send()
+		}
+	}
+
+	for len(pending) > 0 {
+		send()
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+
+	return results, nil
+}
+
+// sendGroupedBatch sends the items at the given indexes, other than those in blocked groups, as one
+// batch, storing their results and marking the groups of failed items as blocked.
+func (c *Client) sendGroupedBatch(items []GroupedItem, index []int, results []BatchResult, blocked map[string]bool) {
+	var bodies []string
+	var sent []int
+	for _, i := range index {
+		if blocked[items[i].GroupID] {
+			results[i].Err = ErrGroupBlocked
+			continue
+		}
+		bodies = append(bodies, items[i].Body)
+		sent = append(sent, i)
+	}
+
+	if len(sent) == 0 {
+		return
+	}
+
+	entries, err := c.makeBatchRequestEntries(bodies)
+	var batch []BatchResult
+	if err == nil {
+		for j, e := range entries {
+			item := items[sent[j]]
+			e.MessageGroupId = aws.String(item.GroupID)
+			e.MessageDeduplicationId = c.deduplicationID(item.Body)
+		}
+		batch, err = c.sendBatch(context.Background(), entries)
+	}
+
+	for j, i := range sent {
+		if err != nil {
+			results[i].Err = err
+		} else {
+			results[i] = batch[j]
+		}
+
+		if results[i].Err != nil {
+			blocked[items[i].GroupID] = true
+		}
+	}
+}
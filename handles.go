@@ -0,0 +1,74 @@
+package sqs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// handleTracker records when the visibility timeout of each receipt handle is estimated to expire.
+type handleTracker struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// add records that the messages were received at t with the given visibility timeout and forgets
+// any handles that expired before t.
+func (h *handleTracker) add(msgs []*sqs.Message, t time.Time, timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.expires == nil {
+		h.expires = make(map[string]time.Time)
+	}
+
+	for handle, expires := range h.expires {
+		if !t.Before(expires) {
+			delete(h.expires, handle)
+		}
+	}
+
+	for _, msg := range msgs {
+		h.expires[aws.StringValue(msg.ReceiptHandle)] = t.Add(timeout)
+	}
+}
+
+// remove forgets the receipt handles of msgs.
+func (h *handleTracker) remove(msgs ...*sqs.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, msg := range msgs {
+		delete(h.expires, aws.StringValue(msg.ReceiptHandle))
+	}
+}
+
+// get returns the time the visibility timeout of the message expires.
+func (h *handleTracker) get(msg *sqs.Message) (time.Time, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.expires[aws.StringValue(msg.ReceiptHandle)]
+	return t, ok
+}
+
+// IsHandleExpired reports whether the visibility timeout of a received message has elapsed, after
+// which its receipt handle can no longer be used to delete it. This is an estimate based on the time
+// the message was received by this Client and the visibility timeout it was received with; it does
+// not account for network latency or for visibility changes made elsewhere. Messages not received by
+// this Client are reported as expired.
+func (c *Client) IsHandleExpired(msg *sqs.Message) bool {
+	expires, ok := c.handles.get(msg)
+	if !ok {
+		return true
+	}
+
+	return !c.now().Before(expires)
+}
+
+// visibilityTimeout returns the configured visibility timeout as a time.Duration.
+func (c *Client) visibilityTimeout() time.Duration {
+	return time.Duration(c.config.VisibilityTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,174 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// InsertJSONBatch encodes each item as JSON and inserts them into the queue in batches. Any number
+// of items can be inserted. The results are in the same order as items; an item that could not be
+// encoded, was too large or was rejected by AWS has a non-nil Err and the other items are still
+// inserted. The returned error is the first item error, if any.
+func (c *Client) InsertJSONBatch(items []interface{}) ([]BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	var bodies []string
+	var index []int
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err == nil && !c.config.Compress && len(b) > MaxMessageSize {
+			err = ErrMessageTooLarge
+		}
+
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		bodies = append(bodies, string(b))
+		index = append(index, i)
+	}
+
+	for len(bodies) > 0 {
+		n := nextBatchLen(bodies)
+		batch, err := c.InsertBatchResults(bodies[:n])
+		for j := 0; j < n; j++ {
+			if err != nil {
+				results[index[j]].Err = err
+			} else {
+				results[index[j]] = batch[j]
+			}
+		}
+
+		bodies, index = bodies[n:], index[n:]
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+
+	return results, nil
+}
+
+// Decoded is a message received by ReceiveJSON along with its decoded body.
+type Decoded struct {
+	// Value is the decoded body, as returned by the newValue function passed to ReceiveJSON.
+	Value interface{}
+	// Message is the received message, which must be deleted once Value has been processed.
+	Message *sqs.Message
+	// Err is the error decoding the body, or from Config.BodyValidator, which is checked first. If
+	// the queue has a quarantine queue the message has been moved there and Err is a
+	// *QuarantineError.
+	Err error
+}
+
+// ReceiveJSON receives up to n messages, between 1 and 10, and decodes the JSON body of each into
+// the value returned by newValue, which should be a pointer such as new(Order). A message that
+// cannot be decoded does not fail the batch: its Err is set and, if Config.QuarantineQueue is set,
+// it is moved to the quarantine queue, otherwise it is left in the queue. Messages are not deleted.
+func (c *Client) ReceiveJSON(ctx context.Context, n int, newValue func() interface{}) ([]Decoded, error) {
+	if n < 1 || n > MaxBatchSize {
+		return nil, fmt.Errorf("sqs: cannot receive %d messages, must be between 1 and %d", n, MaxBatchSize)
+	}
+
+	resp, err := c.receiveNitemsContext(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]Decoded, len(resp.Messages))
+	for i, msg := range resp.Messages {
+		decoded[i].Message = msg
+		if err := c.validate(msg); err != nil {
+			decoded[i].Err = err
+			continue
+		}
+
+		v := newValue()
+		if err := json.Unmarshal([]byte(aws.StringValue(msg.Body)), v); err != nil {
+			decoded[i].Err = c.rejectUndecodable(msg, err)
+			continue
+		}
+		decoded[i].Value = v
+		c.retain([]*sqs.Message{msg})
+	}
+
+	return decoded, nil
+}
+
+// Typed is a message received by ReceiveTyped along with its body decoded as a T.
+type Typed[T any] struct {
+	// Value is the decoded body, or the zero value of T if Err is set.
+	Value T
+	// Message is the received message, which must be deleted once Value has been processed.
+	Message *sqs.Message
+	// Err is the error for a message that could not be decoded or failed validation, as in Decoded.
+	Err error
+}
+
+// ReceiveTyped is like ReceiveJSON but decodes the body of each message into a T, so the values need
+// no type assertion. It is a function rather than a method of Client because methods cannot have
+// type parameters.
+func ReceiveTyped[T any](ctx context.Context, c *Client, n int) ([]Typed[T], error) {
+	decoded, err := c.ReceiveJSON(ctx, n, func() interface{} { return new(T) })
+	if err != nil {
+		return nil, err
+	}
+
+	typed := make([]Typed[T], len(decoded))
+	for i, d := range decoded {
+		typed[i].Message = d.Message
+		typed[i].Err = d.Err
+		if d.Err == nil {
+			typed[i].Value = *d.Value.(*T)
+		}
+	}
+
+	return typed, nil
+}
+
+// rejectUndecodable moves a message whose body could not be decoded to the quarantine queue, if
+// there is one, and returns the error to report for it.
+func (c *Client) rejectUndecodable(msg *sqs.Message, err error) error {
+	if c.quarantineURL == "" {
+		return err
+	}
+
+	if qerr := c.quarantine(msg); qerr != nil {
+		return qerr
+	}
+
+	return &QuarantineError{MessageID: aws.StringValue(msg.MessageId), Err: err}
+}
+
+// undecodableMetric counts messages dropped by DecodeInto because their body was not valid JSON for
+// the expected type.
+const undecodableMetric = "messages_undecodable"
+
+// DecodeInto returns a Handler, for use with Process or HandleNext, that decodes the JSON body of
+// each message into a T and calls h with it. A message that cannot be decoded is never passed to h:
+// it is copied to the quarantine queue if Config.QuarantineQueue is set, counted in the
+// "messages_undecodable" metric and deleted, so a malformed message cannot block the consumer by
+// being retried. Like ReceiveTyped, it is a function because methods cannot have type parameters.
+func DecodeInto[T any](c *Client, h func(ctx context.Context, v T, msg *sqs.Message) error) Handler {
+	return func(ctx context.Context, msg *sqs.Message) error {
+		var v T
+		if err := json.Unmarshal([]byte(aws.StringValue(msg.Body)), &v); err != nil {
+			if c.quarantineURL != "" {
+				if err := c.sendToQuarantine(msg); err != nil {
+					return err
+				}
+			}
+
+			c.metricsFor(ctx).Count(undecodableMetric, 1)
+			return nil
+		}
+
+		return h(ctx, v, msg)
+	}
+}
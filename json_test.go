@@ -0,0 +1,62 @@
+package sqs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+type decodedOrder struct {
+	N int `json:"n"`
+}
+
+func TestDecodeInto(t *testing.T) {
+	tests := []struct {
+		name       string
+		quarantine string
+		remaining  []string
+	}{
+		// The malformed message is deleted rather than left to block the consumer.
+		{name: "without quarantine"},
+		// The malformed message is copied to the quarantine queue, which the mock shares with the
+		// source queue, before it is deleted.
+		{name: "with quarantine", quarantine: "orders-quarantine", remaining: []string{`{"n":`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService(`{"n":1}`, `{"n":`)
+			metrics := &countingMetrics{}
+			config := testConfig("orders")
+			config.Metrics = metrics
+			config.QuarantineQueue = tt.quarantine
+			c := newTestClient(t, config, mock)
+
+			var handled []decodedOrder
+			h := DecodeInto(c, func(ctx context.Context, v decodedOrder, msg *sqs.Message) error {
+				handled = append(handled, v)
+				return nil
+			})
+
+			for i := 0; i < 2; i++ {
+				if ok, err := c.HandleNext(context.Background(), h); !ok || err != nil {
+					t.Fatalf("HandleNext() = %v, %v", ok, err)
+				}
+			}
+
+			if want := []decodedOrder{{N: 1}}; !reflect.DeepEqual(handled, want) {
+				t.Errorf("handled %v, want %v", handled, want)
+			}
+			if n := metrics.count(undecodableMetric); n != 1 {
+				t.Errorf("%s = %d, want 1", undecodableMetric, n)
+			}
+
+			remaining := mock.Remaining()
+			if len(remaining) != len(tt.remaining) || len(remaining) > 0 && !reflect.DeepEqual(remaining, tt.remaining) {
+				t.Errorf("Remaining() = %q, want %q", remaining, tt.remaining)
+			}
+		})
+	}
+}
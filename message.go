@@ -0,0 +1,414 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// awsTraceHeaderAttribute is the system attribute holding the X-Ray trace header of a message.
+const awsTraceHeaderAttribute = "AWSTraceHeader"
+
+// maxAttributes is the number of message attributes SQS allows on a message.
+const maxAttributes = 10
+
+// ErrTooManyAttributes is returned when a message has more than 10 message attributes, including
+// any attached by the Client.
+var ErrTooManyAttributes = errors.New("sqs: messages can have at most 10 attributes")
+
+// Attribute is a message attribute.
+type Attribute struct {
+	// DataType is "String", "Number" or "Binary", optionally followed by a custom type label, for
+	// example "Number.float".
+	DataType string
+	// Value is the value of String and Number attributes.
+	Value string
+	// Binary is the value of Binary attributes.
+	Binary []byte
+}
+
+// StringAttribute returns a String attribute with the given value.
+func StringAttribute(value string) Attribute {
+	return Attribute{DataType: "String", Value: value}
+}
+
+// billingChunk is the payload size SQS bills as a single request.
+const billingChunk = 64 * 1024
+
+// MessageBillableSize returns the number of requests SQS bills for sending a message with the given
+// body and attributes. The payload counted is the body plus, for each attribute, its name, data type
+// and value; every 64 KB of payload, or part of it, is billed as one request. Messages sent in a
+// batch are billed on the same basis for the whole batch, so batching saves most when messages are
+// much smaller than 64 KB. Compression and the attributes the Client adds are not included.
+func MessageBillableSize(body string, attrs map[string]Attribute) int {
+	size := len(body)
+	for name, a := range attrs {
+		size += len(name) + len(a.DataType)
+		if strings.HasPrefix(a.DataType, "Binary") {
+			size += len(a.Binary)
+		} else {
+			size += len(a.Value)
+		}
+	}
+
+	if size == 0 {
+		return 1
+	}
+
+	return (size + billingChunk - 1) / billingChunk
+}
+
+// toMessageAttributes converts attrs to their SDK representation.
+func toMessageAttributes(attrs map[string]Attribute) map[string]*sqs.MessageAttributeValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	out := make(map[string]*sqs.MessageAttributeValue, len(attrs))
+	for name, a := range attrs {
+		v := &sqs.MessageAttributeValue{DataType: aws.String(a.DataType)}
+		if strings.HasPrefix(a.DataType, "Binary") {
+			v.BinaryValue = a.Binary
+		} else {
+			v.StringValue = aws.String(a.Value)
+		}
+		out[name] = v
+	}
+
+	return out
+}
+
+// validateAttributes checks the number and names of message attributes against the limits of SQS.
+func validateAttributes(attrs map[string]*sqs.MessageAttributeValue) error {
+	if len(attrs) > maxAttributes {
+		return ErrTooManyAttributes
+	}
+
+	for name := range attrs {
+		if err := validateAttributeName(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAttributeName checks a message attribute name against the naming rules of SQS.
+func validateAttributeName(name string) error {
+	lower := strings.ToLower(name)
+	switch {
+	case name == "" || len(name) > 256:
+		return fmt.Errorf("sqs: attribute name %q must be between 1 and 256 characters", name)
+	case strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon."):
+		return fmt.Errorf("sqs: attribute name %q uses a reserved prefix", name)
+	case strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, ".."):
+		return fmt.Errorf("sqs: attribute name %q has a misplaced period", name)
+	}
+
+	for _, r := range name {
+		valid := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '_' || r == '-' || r == '.'
+		if !valid {
+			return fmt.Errorf("sqs: attribute name %q contains invalid character %q", name, r)
+		}
+	}
+
+	return nil
+}
+
+// allAttributes requests every message attribute on receive. It happens to have the same value as
+// sqs.QueueAttributeNameAll, which names queue attributes rather than message attributes.
+const allAttributes = "All"
+
+// requested reports whether an attribute is among the names of a receive request, which like SQS
+// may be "All" or, for message attributes, a prefix ending in ".*".
+func requested(names []*string, name string) bool {
+	for _, n := range aws.StringValueSlice(names) {
+		switch {
+		case n == allAttributes || n == ".*" || n == name:
+			return true
+		case strings.HasSuffix(n, ".*") && strings.HasPrefix(name, strings.TrimSuffix(n, "*")):
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateAttributeSelector returns an error if name cannot be used to request message attributes
+// on receive. As well as attribute names it accepts "All", ".*" and prefixes ending in ".*".
+func validateAttributeSelector(name string) error {
+	if name == allAttributes || name == ".*" {
+		return nil
+	}
+
+	return validateAttributeName(strings.TrimSuffix(name, ".*"))
+}
+
+// ErrNoTimestamp is returned when a message is missing a timestamp attribute.
+var ErrNoTimestamp = errors.New("sqs: message has no timestamp attribute")
+
+// SentTime returns the time a received message was sent to the queue.
+func SentTime(msg *sqs.Message) (time.Time, error) {
+	return timestampAttribute(msg, sqs.MessageSystemAttributeNameSentTimestamp)
+}
+
+// FirstReceivedTime returns the time a received message was first received from the queue. The
+// time from SentTime to FirstReceivedTime is how long the message waited before it was first
+// received. It does not change when the message is redelivered, so on a message received more than
+// once, the time since FirstReceivedTime shows how long it has been failing to be processed.
+func FirstReceivedTime(msg *sqs.Message) (time.Time, error) {
+	return timestampAttribute(msg, sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp)
+}
+
+// producerAttribute is the message attribute holding Config.ProducerName.
+const producerAttribute = "producer"
+
+// Producer returns the name of the producer that sent a received message, if the producer had
+// Config.ProducerName set.
+func Producer(msg *sqs.Message) (string, bool) {
+	v, ok := msg.MessageAttributes[producerAttribute]
+	if !ok || v.StringValue == nil {
+		return "", false
+	}
+
+	return *v.StringValue, true
+}
+
+// GroupByAttribute partitions msgs by the string value of the named message attribute, so that a
+// single receive can be dispatched to different handlers. Messages without the attribute are grouped
+// under the empty string. The order of msgs is preserved within each group.
+func GroupByAttribute(msgs []*sqs.Message, name string) map[string][]*sqs.Message {
+	groups := make(map[string][]*sqs.Message)
+	for _, msg := range msgs {
+		var key string
+		if v, ok := msg.MessageAttributes[name]; ok {
+			key = aws.StringValue(v.StringValue)
+		}
+		groups[key] = append(groups[key], msg)
+	}
+
+	return groups
+}
+
+// TraceHeader returns the AWS X-Ray trace header of a received message, if it has one.
+func TraceHeader(msg *sqs.Message) (string, bool) {
+	return systemAttribute(msg, awsTraceHeaderAttribute)
+}
+
+// SequenceNumber returns the sequence number SQS assigned to a message received from a FIFO queue.
+// Sequence numbers increase within a message group, so gaps or reversals can be used to debug
+// ordering.
+func SequenceNumber(msg *sqs.Message) (string, bool) {
+	return systemAttribute(msg, sqs.MessageSystemAttributeNameSequenceNumber)
+}
+
+// ReceiveCount returns the approximate number of times a received message has been received,
+// including this time.
+func ReceiveCount(msg *sqs.Message) (int, bool) {
+	v, ok := systemAttribute(msg, sqs.MessageSystemAttributeNameApproximateReceiveCount)
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
+
+// schemaVersionAttribute is the message attribute holding Config.SchemaVersion.
+const schemaVersionAttribute = "schema-version"
+
+// SchemaVersion returns the version of the message format a received message was sent with, if
+// the producer had Config.SchemaVersion set.
+func SchemaVersion(msg *sqs.Message) (string, bool) {
+	v, ok := msg.MessageAttributes[schemaVersionAttribute]
+	if !ok || v.StringValue == nil {
+		return "", false
+	}
+
+	return *v.StringValue, true
+}
+
+// SenderID returns the ID of the IAM user or role that sent a received message: an account ID for an
+// IAM user, or a role ID and session name for an assumed role.
+func SenderID(msg *sqs.Message) (string, bool) {
+	return systemAttribute(msg, sqs.MessageSystemAttributeNameSenderId)
+}
+
+// systemAttribute returns the named system attribute of msg.
+func systemAttribute(msg *sqs.Message, name string) (string, bool) {
+	v, ok := msg.Attributes[name]
+	if !ok || v == nil {
+		return "", false
+	}
+
+	return aws.StringValue(v), true
+}
+
+// withDefaultAttributes returns attrs with the attributes this Client attaches to every message
+// added. Attributes already in attrs are not overwritten.
+func (c *Client) withDefaultAttributes(attrs map[string]*sqs.MessageAttributeValue) map[string]*sqs.MessageAttributeValue {
+	var merged map[string]*sqs.MessageAttributeValue
+	for name, v := range c.defaultAttributes() {
+		if _, ok := attrs[name]; ok {
+			continue
+		}
+
+		if merged == nil {
+			merged = make(map[string]*sqs.MessageAttributeValue, len(attrs)+len(c.config.DefaultAttributes)+2)
+			for k, v := range attrs {
+				merged[k] = v
+			}
+		}
+		merged[name] = v
+	}
+
+	if merged == nil {
+		return attrs
+	}
+
+	return merged
+}
+
+// defaultAttributes returns the attributes configured to be attached to every message. The
+// producer and schema version attributes replace attributes of the same name in
+// Config.DefaultAttributes.
+func (c *Client) defaultAttributes() map[string]*sqs.MessageAttributeValue {
+	defaults := make(map[string]*sqs.MessageAttributeValue, len(c.config.DefaultAttributes)+2)
+	for name, v := range toMessageAttributes(c.config.DefaultAttributes) {
+		defaults[name] = v
+	}
+
+	if c.config.ProducerName != "" {
+		defaults[producerAttribute] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(c.config.ProducerName),
+		}
+	}
+
+	if c.config.SchemaVersion != "" {
+		defaults[schemaVersionAttribute] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(c.config.SchemaVersion),
+		}
+	}
+
+	return defaults
+}
+
+// timestampAttribute parses the named system attribute of msg, which holds milliseconds since the
+// epoch.
+func timestampAttribute(msg *sqs.Message, name string) (time.Time, error) {
+	v, ok := systemAttribute(msg, name)
+	if !ok {
+		return time.Time{}, ErrNoTimestamp
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("sqs: invalid %s %q: %v", name, v, err)
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
+
+// PeekWithAge is like Peek but also returns how long the message has been in the queue. If the
+// message's sent time is missing or malformed, the message is returned with an age of 0 and the
+// error from SentTime.
+func (c *Client) PeekWithAge() (*sqs.Message, time.Duration, error) {
+	msg, err := c.Peek()
+	if err != nil || msg == nil {
+		return nil, 0, err
+	}
+
+	sent, err := SentTime(msg)
+	if err != nil {
+		return msg, 0, err
+	}
+
+	return msg, c.now().Sub(sent), nil
+}
+
+// OldestMessageAge returns the age of the oldest message in a sample of up to 10 visible messages,
+// or 0 if none are visible. The sample is taken with a short poll, which returns immediately, and a
+// visibility timeout of 0, so the sampled messages stay visible to other consumers. This is a best
+// effort estimate: it is not the true oldest message in the queue, since SQS returns an arbitrary
+// subset of messages and messages in flight are not seen, but it needs no CloudWatch permissions.
+//
+// The sampled messages are really received, so each call adds one to their receive count and, if
+// the queue has a redrive policy, brings them closer to being moved to the dead letter queue.
+func (c *Client) OldestMessageAge() (time.Duration, error) {
+	input := c.receiveInput(MaxBatchSize)
+	input.VisibilityTimeout = aws.Int64(0)
+	input.WaitTimeSeconds = aws.Int64(0)
+
+	resp, err := c.fetch(context.Background(), input)
+	if err != nil {
+		return 0, err
+	}
+
+	var oldest time.Duration
+	for _, msg := range resp.Messages {
+		sent, err := SentTime(msg)
+		if err != nil {
+			continue
+		}
+
+		if age := c.now().Sub(sent); age > oldest {
+			oldest = age
+		}
+	}
+
+	return oldest, nil
+}
+
+// Requeue inserts a copy of a received message, with the same body and message attributes, at the
+// back of the queue and then deletes the original. The copy is a new message, so its receive count
+// starts again from zero. If the process stops between the insert and the delete, both messages will
+// be in the queue and the message will be processed twice.
+func (c *Client) Requeue(msg *sqs.Message) error {
+	return c.requeue(msg, 0)
+}
+
+// requeue is like Requeue but delays the copy by up to 15 minutes.
+func (c *Client) requeue(msg *sqs.Message, delay time.Duration) error {
+	err := c.sendMessage(&sqs.SendMessageInput{
+		DelaySeconds:      aws.Int64(int64(delaySeconds(delay))),
+		MessageAttributes: msg.MessageAttributes,
+		MessageBody:       msg.Body,
+		QueueUrl:          c.currentURL(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Delete(msg)
+}
+
+// MoveTo moves a received message to the queue of dst, such as a parking queue for manual triage.
+// The body and message attributes are inserted into dst and the message is only deleted from this
+// queue once the insert has succeeded, so it is never lost. If the process stops between the insert
+// and the delete, the message will be in both queues. ErrMissingGroupID is returned if dst is a
+// FIFO queue.
+func (c *Client) MoveTo(dst *Client, msg *sqs.Message) error {
+	if dst.config.isFIFO() {
+		return ErrMissingGroupID
+	}
+
+	err := dst.sendMessage(&sqs.SendMessageInput{
+		MessageAttributes: msg.MessageAttributes,
+		MessageBody:       msg.Body,
+		QueueUrl:          dst.currentURL(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Delete(msg)
+}
@@ -0,0 +1,87 @@
+package sqs
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestInsertWithAttributesValidation(t *testing.T) {
+	eleven := make(map[string]Attribute, maxAttributes+1)
+	for i := 0; i <= maxAttributes; i++ {
+		eleven["attr"+strconv.Itoa(i)] = Attribute{DataType: "String", Value: "v"}
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]Attribute
+		valid bool
+		err   error // the specific error expected, if any
+	}{
+		{name: "valid", attrs: map[string]Attribute{"trace-id": {DataType: "String", Value: "v"}}, valid: true},
+		{name: "11 attributes", attrs: eleven, err: ErrTooManyAttributes},
+		{name: "AWS prefix", attrs: map[string]Attribute{"AWS.TraceHeader": {DataType: "String", Value: "v"}}},
+		{name: "Amazon prefix", attrs: map[string]Attribute{"amazon.trace": {DataType: "String", Value: "v"}}},
+		{name: "too long", attrs: map[string]Attribute{strings.Repeat("a", 257): {DataType: "String", Value: "v"}}},
+		{name: "misplaced period", attrs: map[string]Attribute{"trace..id": {DataType: "String", Value: "v"}}},
+		{name: "invalid character", attrs: map[string]Attribute{"trace id": {DataType: "String", Value: "v"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			c := newTestClient(t, testConfig("orders"), mock)
+
+			err := c.InsertWithAttributes("body", tt.attrs)
+			if (err == nil) != tt.valid {
+				t.Fatalf("InsertWithAttributes() = %v, want valid %v", err, tt.valid)
+			}
+			if tt.err != nil && err != tt.err {
+				t.Errorf("InsertWithAttributes() = %v, want %v", err, tt.err)
+			}
+
+			if sent := len(mock.Sent()); (sent == 1) != tt.valid {
+				t.Errorf("%d messages sent, want the request made only for valid attributes", sent)
+			}
+		})
+	}
+}
+
+func TestStrictAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		valid bool // whether Validate accepts names with StrictAttributes
+		want  []string
+	}{
+		{name: "default", valid: true, want: []string{"All"}},
+		{name: "valid name", names: []string{"trace-id"}, valid: true, want: []string{"trace-id"}},
+		{name: "wildcard", names: []string{"All"}, valid: true, want: []string{"All"}},
+		{name: "prefix", names: []string{"trace.*"}, valid: true, want: []string{"trace.*"}},
+		{name: "invalid name", names: []string{"trace id"}, want: []string{"trace id"}},
+		{name: "reserved prefix", names: []string{"AWS.TraceHeader"}, want: []string{"AWS.TraceHeader"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig("orders")
+			config.MessageAttributeNames = tt.names
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() without StrictAttributes = %v", err)
+			}
+
+			c := newTestClient(t, config, NewMockAPIService())
+			if got := aws.StringValueSlice(c.messageAttributeNames()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messageAttributeNames() = %q, want %q", got, tt.want)
+			}
+
+			config.StrictAttributes = true
+			if err := config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() with StrictAttributes = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
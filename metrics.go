@@ -0,0 +1,105 @@
+package sqs
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics receives measurements from a Client. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Count adds n to the named counter.
+	Count(name string, n int)
+	// Gauge sets the named gauge to v.
+	Gauge(name string, v float64)
+	// Timing records how long the named operation took.
+	Timing(name string, d time.Duration)
+}
+
+// Operations timed with Metrics.Timing. A failed operation is also counted in the counter with the
+// operation's name followed by errorsSuffix, such as "receive_errors".
+const (
+	sendOperation        = "send"
+	sendBatchOperation   = "send_batch"
+	receiveOperation     = "receive"
+	deleteOperation      = "delete"
+	deleteBatchOperation = "delete_batch"
+)
+
+// errorsSuffix is appended to the name of an operation to name the counter of its failures.
+const errorsSuffix = "_errors"
+
+// RequestMetrics is a Metrics that can attribute measurements to the request that caused them, for
+// example to include a correlation ID in logs or traces. Operations called with a context carrying
+// a request ID, such as InsertContext and PopContext, report their measurements to the Metrics
+// returned by ForRequest.
+type RequestMetrics interface {
+	Metrics
+	// ForRequest returns a Metrics that records measurements as belonging to requestID.
+	ForRequest(requestID string) Metrics
+}
+
+// requestIDKey is the context key of the request ID set by WithRequestID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying requestID. Operations of a Client called with the
+// returned context attribute their measurements to requestID if Config.Metrics implements
+// RequestMetrics, so that a single high level request can be traced through every SQS call it
+// makes.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID set on ctx by WithRequestID.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// nopMetrics discards all measurements.
+type nopMetrics struct{}
+
+func (nopMetrics) Count(string, int)            {}
+func (nopMetrics) Gauge(string, float64)        {}
+func (nopMetrics) Timing(string, time.Duration) {}
+
+// metrics returns the Metrics to report to: the CloudWatch publisher if there is one, which passes
+// measurements on to the configured Metrics, otherwise the configured Metrics, or a no-op
+// implementation if there is none.
+func (c *Client) metrics() Metrics {
+	if c.cloudWatch != nil {
+		return c.cloudWatch
+	}
+
+	if c.config.Metrics == nil {
+		return nopMetrics{}
+	}
+
+	return c.config.Metrics
+}
+
+// metricsFor returns the Metrics to report measurements of an operation bounded by ctx to. If ctx
+// carries a request ID and the Metrics implement RequestMetrics, the measurements are attributed to
+// the request.
+func (c *Client) metricsFor(ctx context.Context) Metrics {
+	m := c.metrics()
+	id, ok := RequestID(ctx)
+	if !ok {
+		return m
+	}
+
+	if r, ok := m.(RequestMetrics); ok {
+		return r.ForRequest(id)
+	}
+
+	return m
+}
+
+// observe reports how long an operation bounded by ctx took since start and, if it failed, counts
+// the failure.
+func (c *Client) observe(ctx context.Context, operation string, start time.Time, err error) {
+	m := c.metricsFor(ctx)
+	m.Timing(operation, c.now().Sub(start))
+	if err != nil {
+		m.Count(operation+errorsSuffix, 1)
+	}
+}
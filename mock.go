@@ -0,0 +1,729 @@
+package sqs
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MockAPIService is an in-memory implementation of the SQS API for testing code that uses a Client
+// without AWS. It models a single queue: every queue name and URL refers to the same messages. Use
+// NewMockClient to create a Client backed by it.
+type MockAPIService struct {
+	mu       sync.Mutex
+	messages []*mockMessage
+	sent     []string
+	attrs    map[string]*string
+	name     string
+	nextID   int
+	changed  chan struct{}
+	// failSends is the number of upcoming batch entries to reject as throttled.
+	failSends int
+	// failures holds the errors that upcoming calls to each operation fail with, in order.
+	failures map[string][]error
+	clock    Clock
+}
+
+// mockMessage is a message held by MockAPIService.
+type mockMessage struct {
+	id        string
+	body      string
+	attrs     map[string]*sqs.MessageAttributeValue
+	handle    string
+	sentAt    time.Time
+	visibleAt time.Time
+	receives  int
+	firstRecv time.Time
+}
+
+// NewMockAPIService returns a MockAPIService whose queue initially holds bodies. Initial messages
+// are not included in Sent.
+func NewMockAPIService(bodies ...string) *MockAPIService {
+	m := &MockAPIService{
+		attrs:    make(map[string]*string),
+		changed:  make(chan struct{}),
+		failures: make(map[string][]error),
+		clock:    realClock{},
+	}
+
+	for _, body := range bodies {
+		m.add(body, nil)
+	}
+
+	return m
+}
+
+// LoadFile adds each line of the file at path to the queue as a message.
+func (m *MockAPIService) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m.add(scanner.Text(), nil)
+	}
+
+	return scanner.Err()
+}
+
+// Sent returns the bodies of all messages sent to the queue, in the order they were sent.
+func (m *MockAPIService) Sent() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.sent...)
+}
+
+// Remaining returns the bodies of the messages that have not been deleted, including those that are
+// currently in flight.
+func (m *MockAPIService) Remaining() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bodies := make([]string, 0, len(m.messages))
+	for _, msg := range m.messages {
+		bodies = append(bodies, msg.body)
+	}
+
+	return bodies
+}
+
+// add appends a message to the queue and wakes any waiting receives. m.mu must be held.
+func (m *MockAPIService) add(body string, attrs map[string]*sqs.MessageAttributeValue) string {
+	m.nextID++
+	id := fmt.Sprintf("00000000-0000-4000-8000-%012d", m.nextID)
+	m.messages = append(m.messages, &mockMessage{
+		id:     id,
+		body:   body,
+		attrs:  copyMessageAttributes(attrs),
+		sentAt: m.now(),
+	})
+
+	close(m.changed)
+	m.changed = make(chan struct{})
+	return id
+}
+
+// md5Hex returns the hex encoded MD5 digest of body, as SQS reports in MD5OfMessageBody.
+func md5Hex(body string) string {
+	sum := md5.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// send records and adds a sent message. m.mu must be held.
+func (m *MockAPIService) send(body string, attrs map[string]*sqs.MessageAttributeValue) string {
+	m.sent = append(m.sent, body)
+	return m.add(body, attrs)
+}
+
+// remove deletes the message with the given receipt handle. m.mu must be held.
+func (m *MockAPIService) remove(handle string) error {
+	for i, msg := range m.messages {
+		if msg.handle != "" && msg.handle == handle {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, "receipt handle is invalid", nil)
+}
+
+// find returns the in-flight message with the given receipt handle. m.mu must be held.
+func (m *MockAPIService) find(handle string) *mockMessage {
+	for _, msg := range m.messages {
+		if msg.handle != "" && msg.handle == handle {
+			return msg
+		}
+	}
+
+	return nil
+}
+
+// VisibilityRemaining returns how long the in-flight message with the given receipt handle will stay
+// invisible, so tests can check that visibility timeouts are extended or released as expected. It
+// returns false if no in-flight message has the handle, for example because it was deleted or
+// received again with a new handle. The result is 0 once the message is visible again.
+func (m *MockAPIService) VisibilityRemaining(receiptHandle string) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg := m.find(receiptHandle)
+	if msg == nil {
+		return 0, false
+	}
+
+	remaining := msg.visibleAt.Sub(m.now())
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return remaining, true
+}
+
+// FailBatchEntries makes SendMessageBatch reject the next n entries it is sent as throttled, a
+// failure that may succeed if retried, while accepting the others in the same batch.
+func (m *MockAPIService) FailBatchEntries(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failSends = n
+}
+
+// FailNext makes the next call to an operation, named as in the SQS API such as "SendMessage" or
+// "ReceiveMessage", fail with err without doing anything. Calling it several times for the same
+// operation fails that many calls, in order. The WithContext variants share the hooks of their
+// operations.
+func (m *MockAPIService) FailNext(operation string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures[operation] = append(m.failures[operation], err)
+}
+
+// failure returns the error the current call to operation should fail with, if any. m.mu must be
+// held.
+func (m *MockAPIService) failure(operation string) error {
+	errs := m.failures[operation]
+	if len(errs) == 0 {
+		return nil
+	}
+
+	m.failures[operation] = errs[1:]
+	return errs[0]
+}
+
+// SetClock makes the mock read the time from clock, for example to age messages or expire their
+// visibility without sleeping. A Client created by NewMockClient afterwards uses the same clock
+// unless its Config sets one.
+func (m *MockAPIService) SetClock(clock Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clock = clock
+}
+
+// now returns the current time according to the mock's clock. m.mu must be held.
+func (m *MockAPIService) now() time.Time {
+	return m.clock.Now()
+}
+
+// copyMessageAttributes returns a deep copy of attrs, so that messages stored by the mock share
+// nothing with the messages sent to or received from it.
+func copyMessageAttributes(attrs map[string]*sqs.MessageAttributeValue) map[string]*sqs.MessageAttributeValue {
+	if attrs == nil {
+		return nil
+	}
+
+	copied := make(map[string]*sqs.MessageAttributeValue, len(attrs))
+	for name, v := range attrs {
+		if v == nil {
+			copied[name] = nil
+			continue
+		}
+
+		c := &sqs.MessageAttributeValue{}
+		if v.DataType != nil {
+			c.DataType = aws.String(*v.DataType)
+		}
+		if v.StringValue != nil {
+			c.StringValue = aws.String(*v.StringValue)
+		}
+		if v.BinaryValue != nil {
+			c.BinaryValue = append([]byte(nil), v.BinaryValue...)
+		}
+		for _, b := range v.BinaryListValues {
+			c.BinaryListValues = append(c.BinaryListValues, append([]byte(nil), b...))
+		}
+		for _, str := range v.StringListValues {
+			if str != nil {
+				str = aws.String(*str)
+			}
+			c.StringListValues = append(c.StringListValues, str)
+		}
+		copied[name] = c
+	}
+
+	return copied
+}
+
+// SendMessage adds a message to the queue.
+func (m *MockAPIService) SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("SendMessage"); err != nil {
+		return nil, err
+	}
+
+	id := m.send(aws.StringValue(input.MessageBody), input.MessageAttributes)
+	return &sqs.SendMessageOutput{
+		MD5OfMessageBody: aws.String(md5Hex(aws.StringValue(input.MessageBody))),
+		MessageId:        &id,
+	}, nil
+}
+
+// SendMessageBatch adds a batch of messages to the queue.
+func (m *MockAPIService) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("SendMessageBatch"); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range input.Entries {
+		if m.failSends > 0 {
+			m.failSends--
+			out.Failed = append(out.Failed, &sqs.BatchResultErrorEntry{
+				Code:        aws.String("ThrottlingException"),
+				Id:          e.Id,
+				Message:     aws.String("Rate exceeded"),
+				SenderFault: aws.Bool(false),
+			})
+			continue
+		}
+
+		id := m.send(aws.StringValue(e.MessageBody), e.MessageAttributes)
+		out.Successful = append(out.Successful, &sqs.SendMessageBatchResultEntry{
+			Id:               e.Id,
+			MD5OfMessageBody: aws.String(md5Hex(aws.StringValue(e.MessageBody))),
+			MessageId:        aws.String(id),
+		})
+	}
+
+	return out, nil
+}
+
+// SendMessageWithContext is SendMessage, returning ctx's error if it is already done.
+func (m *MockAPIService) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, _ ...request.Option) (*sqs.SendMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.SendMessage(input)
+}
+
+// SendMessageBatchWithContext is SendMessageBatch, returning ctx's error if it is already done.
+func (m *MockAPIService) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, _ ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.SendMessageBatch(input)
+}
+
+// DeleteMessage removes an in-flight message from the queue.
+func (m *MockAPIService) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("DeleteMessage"); err != nil {
+		return nil, err
+	}
+
+	if err := m.remove(aws.StringValue(input.ReceiptHandle)); err != nil {
+		return nil, err
+	}
+
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// DeleteMessageBatch removes a batch of in-flight messages from the queue.
+func (m *MockAPIService) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("DeleteMessageBatch"); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range input.Entries {
+		if err := m.remove(aws.StringValue(e.ReceiptHandle)); err != nil {
+			out.Failed = append(out.Failed, &sqs.BatchResultErrorEntry{
+				Code:        aws.String(sqs.ErrCodeReceiptHandleIsInvalid),
+				Id:          e.Id,
+				Message:     aws.String(err.Error()),
+				SenderFault: aws.Bool(true),
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, &sqs.DeleteMessageBatchResultEntry{Id: e.Id})
+	}
+
+	return out, nil
+}
+
+// DeleteMessageWithContext is DeleteMessage, returning ctx's error if it is already done.
+func (m *MockAPIService) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, _ ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.DeleteMessage(input)
+}
+
+// DeleteMessageBatchWithContext is DeleteMessageBatch, returning ctx's error if it is already done.
+func (m *MockAPIService) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.DeleteMessageBatch(input)
+}
+
+// GetQueueAttributes returns the attributes the queue was created or updated with, along with the
+// number of visible, in-flight and delayed messages.
+func (m *MockAPIService) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("GetQueueAttributes"); err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	visible, inFlight, delayed := 0, 0, 0
+	for _, msg := range m.messages {
+		switch {
+		case !now.Before(msg.visibleAt):
+			visible++
+		case msg.receives == 0:
+			delayed++
+		default:
+			inFlight++
+		}
+	}
+
+	attrs := make(map[string]*string, len(m.attrs)+3)
+	for k, v := range m.attrs {
+		attrs[k] = v
+	}
+	attrs[sqs.QueueAttributeNameApproximateNumberOfMessages] = aws.String(strconv.Itoa(visible))
+	attrs[sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible] = aws.String(strconv.Itoa(inFlight))
+	attrs[sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed] = aws.String(strconv.Itoa(delayed))
+
+	return &sqs.GetQueueAttributesOutput{Attributes: attrs}, nil
+}
+
+// SetQueueAttributes updates the attributes of the queue.
+func (m *MockAPIService) SetQueueAttributes(input *sqs.SetQueueAttributesInput) (*sqs.SetQueueAttributesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("SetQueueAttributes"); err != nil {
+		return nil, err
+	}
+
+	for k, v := range input.Attributes {
+		m.attrs[k] = v
+	}
+
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+// PurgeQueue removes all messages from the queue.
+func (m *MockAPIService) PurgeQueue(*sqs.PurgeQueueInput) (*sqs.PurgeQueueOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("PurgeQueue"); err != nil {
+		return nil, err
+	}
+
+	m.messages = nil
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+// ReceiveMessage returns visible messages from the queue without waiting.
+func (m *MockAPIService) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("ReceiveMessage"); err != nil {
+		return nil, err
+	}
+
+	return m.receive(input), nil
+}
+
+// ReceiveMessageWithContext returns visible messages from the queue. If there are none it waits up
+// to WaitTimeSeconds for one to be sent, or until ctx is canceled.
+func (m *MockAPIService) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, awserr.New(request.CanceledErrorCode, "request context canceled", err)
+	}
+
+	m.mu.Lock()
+	err := m.failure("ReceiveMessage")
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.NewTimer(time.Duration(aws.Int64Value(input.WaitTimeSeconds)) * time.Second)
+	defer deadline.Stop()
+
+	for {
+		m.mu.Lock()
+		out := m.receive(input)
+		changed := m.changed
+		m.mu.Unlock()
+
+		if len(out.Messages) > 0 {
+			return out, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return out, nil
+		case <-ctx.Done():
+			return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+		}
+	}
+}
+
+// receive returns up to MaxNumberOfMessages visible messages and makes them invisible for the
+// requested visibility timeout. Like SQS, only the attributes the request names are returned.
+// m.mu must be held.
+func (m *MockAPIService) receive(input *sqs.ReceiveMessageInput) *sqs.ReceiveMessageOutput {
+	max := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if max == 0 {
+		max = 1
+	}
+
+	timeout := time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second
+	now := m.now()
+	out := &sqs.ReceiveMessageOutput{}
+	for _, msg := range m.messages {
+		if len(out.Messages) == max {
+			break
+		}
+
+		if now.Before(msg.visibleAt) {
+			continue
+		}
+
+		m.nextID++
+		msg.handle = msg.id + "-" + strconv.Itoa(m.nextID)
+		msg.visibleAt = now.Add(timeout)
+		msg.receives++
+		if msg.firstRecv.IsZero() {
+			msg.firstRecv = now
+		}
+		attrs := map[string]*string{
+			sqs.MessageSystemAttributeNameSentTimestamp:                    aws.String(strconv.FormatInt(msg.sentAt.UnixNano()/int64(time.Millisecond), 10)),
+			sqs.MessageSystemAttributeNameApproximateReceiveCount:          aws.String(strconv.Itoa(msg.receives)),
+			sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp: aws.String(strconv.FormatInt(msg.firstRecv.UnixNano()/int64(time.Millisecond), 10)),
+		}
+		for name := range attrs {
+			if !requested(input.AttributeNames, name) {
+				delete(attrs, name)
+			}
+		}
+
+		msgAttrs := copyMessageAttributes(msg.attrs)
+		for name := range msgAttrs {
+			if !requested(input.MessageAttributeNames, name) {
+				delete(msgAttrs, name)
+			}
+		}
+
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+		if len(msgAttrs) == 0 {
+			msgAttrs = nil
+		}
+
+		out.Messages = append(out.Messages, &sqs.Message{
+			Attributes:        attrs,
+			Body:              aws.String(msg.body),
+			MD5OfBody:         aws.String(md5Hex(msg.body)),
+			MessageAttributes: msgAttrs,
+			MessageId:         aws.String(msg.id),
+			ReceiptHandle:     aws.String(msg.handle),
+		})
+	}
+
+	return out
+}
+
+// CreateQueue records the attributes of the queue. Like SQS, it fails with QueueAlreadyExists if an
+// attribute already set on the queue, for example with SetQueueAttributes, has a different value.
+func (m *MockAPIService) CreateQueue(input *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("CreateQueue"); err != nil {
+		return nil, err
+	}
+
+	for k, v := range input.Attributes {
+		if existing, ok := m.attrs[k]; ok && aws.StringValue(existing) != aws.StringValue(v) {
+			return nil, awserr.New(sqs.ErrCodeQueueNameExists, "queue already exists with a different value for "+k, nil)
+		}
+	}
+
+	for k, v := range input.Attributes {
+		m.attrs[k] = v
+	}
+	m.name = aws.StringValue(input.QueueName)
+
+	return &sqs.CreateQueueOutput{QueueUrl: mockQueueURL(aws.StringValue(input.QueueName))}, nil
+}
+
+// DeleteQueue removes all messages from the queue.
+func (m *MockAPIService) DeleteQueue(*sqs.DeleteQueueInput) (*sqs.DeleteQueueOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("DeleteQueue"); err != nil {
+		return nil, err
+	}
+
+	m.messages = nil
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+// GetQueueUrl returns a URL for the named queue.
+func (m *MockAPIService) GetQueueUrl(input *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("GetQueueUrl"); err != nil {
+		return nil, err
+	}
+
+	return &sqs.GetQueueUrlOutput{QueueUrl: mockQueueURL(aws.StringValue(input.QueueName))}, nil
+}
+
+// ChangeMessageVisibility changes the visibility timeout of an in-flight message.
+func (m *MockAPIService) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("ChangeMessageVisibility"); err != nil {
+		return nil, err
+	}
+
+	msg := m.find(aws.StringValue(input.ReceiptHandle))
+	if msg == nil {
+		return nil, awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, "receipt handle is invalid", nil)
+	}
+
+	msg.visibleAt = m.now().Add(time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second)
+	close(m.changed)
+	m.changed = make(chan struct{})
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// ChangeMessageVisibilityBatch changes the visibility timeout of a batch of in-flight messages.
+func (m *MockAPIService) ChangeMessageVisibilityBatch(input *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("ChangeMessageVisibilityBatch"); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.ChangeMessageVisibilityBatchOutput{}
+	now := m.now()
+	for _, e := range input.Entries {
+		msg := m.find(aws.StringValue(e.ReceiptHandle))
+		if msg == nil {
+			out.Failed = append(out.Failed, &sqs.BatchResultErrorEntry{
+				Code:        aws.String(sqs.ErrCodeReceiptHandleIsInvalid),
+				Id:          e.Id,
+				Message:     aws.String("receipt handle is invalid"),
+				SenderFault: aws.Bool(true),
+			})
+			continue
+		}
+
+		msg.visibleAt = now.Add(time.Duration(aws.Int64Value(e.VisibilityTimeout)) * time.Second)
+		out.Successful = append(out.Successful, &sqs.ChangeMessageVisibilityBatchResultEntry{Id: e.Id})
+	}
+
+	close(m.changed)
+	m.changed = make(chan struct{})
+	return out, nil
+}
+
+// ListQueues returns the URL of the mock's queue if it has been created and its name starts with
+// the requested prefix.
+func (m *MockAPIService) ListQueues(input *sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.failure("ListQueues"); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.ListQueuesOutput{}
+	if m.name != "" && strings.HasPrefix(m.name, aws.StringValue(input.QueueNamePrefix)) {
+		out.QueueUrls = []*string{mockQueueURL(m.name)}
+	}
+
+	return out, nil
+}
+
+// CreateQueueWithContext is CreateQueue, returning ctx's error if it is already done.
+func (m *MockAPIService) CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, _ ...request.Option) (*sqs.CreateQueueOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.CreateQueue(input)
+}
+
+// DeleteQueueWithContext is DeleteQueue, returning ctx's error if it is already done.
+func (m *MockAPIService) DeleteQueueWithContext(ctx aws.Context, input *sqs.DeleteQueueInput, _ ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.DeleteQueue(input)
+}
+
+// GetQueueUrlWithContext is GetQueueUrl, returning ctx's error if it is already done.
+func (m *MockAPIService) GetQueueUrlWithContext(ctx aws.Context, input *sqs.GetQueueUrlInput, _ ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.GetQueueUrl(input)
+}
+
+// ListQueuesWithContext is ListQueues, returning ctx's error if it is already done.
+func (m *MockAPIService) ListQueuesWithContext(ctx aws.Context, input *sqs.ListQueuesInput, _ ...request.Option) (*sqs.ListQueuesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return m.ListQueues(input)
+}
+
+// mockQueueURL returns the URL the mock uses for the named queue.
+func mockQueueURL(name string) *string {
+	return aws.String("https://sqs.mock.amazonaws.com/000000000000/" + name)
+}
@@ -0,0 +1,126 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// SourcedMessage is a message received by a MultiConsumer along with the Client of the queue it
+// came from, which must be used to delete it.
+type SourcedMessage struct {
+	Source  *Client
+	Message *sqs.Message
+}
+
+// MultiConsumer receives messages from several queues.
+type MultiConsumer struct {
+	clients []*Client
+}
+
+// NewMultiConsumer returns a MultiConsumer for the queues of the given clients.
+func NewMultiConsumer(clients ...*Client) *MultiConsumer {
+	return &MultiConsumer{clients: clients}
+}
+
+// Consume receives messages from all queues until ctx is canceled and sends them on a single
+// channel. Each queue is polled independently, and since a queue is only polled again once its
+// previous messages have been taken from the channel, busy queues cannot starve quiet ones. Errors
+// from all queues are sent on the returned error channel. Both channels must be drained and are
+// closed once every queue has stopped. See Client.Consume.
+func (m *MultiConsumer) Consume(ctx context.Context, opts ConsumeOptions) (<-chan SourcedMessage, <-chan error) {
+	out := make(chan SourcedMessage)
+	errs := make(chan error)
+
+	var wg sync.WaitGroup
+	for _, c := range m.clients {
+		msgs, cerrs := c.Consume(ctx, opts)
+		wg.Add(2)
+		go func(c *Client) {
+			defer wg.Done()
+			for msg := range msgs {
+				select {
+				case out <- SourcedMessage{Source: c, Message: msg}:
+				case <-ctx.Done():
+				}
+			}
+		}(c)
+		go func() {
+			defer wg.Done()
+			for err := range cerrs {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+		close(errs)
+	}()
+
+	return out, errs
+}
+
+// MultiQueue publishes every message to several queues, for example to dual-write during a queue
+// migration or to feed a shadow queue.
+type MultiQueue struct {
+	clients []*Client
+}
+
+// NewMultiQueue returns a MultiQueue that publishes to the queues of the given clients.
+func NewMultiQueue(clients ...*Client) *MultiQueue {
+	return &MultiQueue{clients: clients}
+}
+
+// MultiInsertError is returned by MultiQueue.Insert when the message could not be inserted into some
+// of the queues.
+type MultiInsertError struct {
+	// Failed maps the name of each queue the message was not inserted into to the reason.
+	Failed map[string]error
+}
+
+func (e *MultiInsertError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Sprintf("sqs: insert failed for %d of the queues: %s", len(names), strings.Join(names, ", "))
+}
+
+// Insert inserts input into every queue concurrently, as Client.Insert does. The message is inserted
+// into as many queues as possible; if any fail, a *MultiInsertError says which.
+func (m *MultiQueue) Insert(input string) error {
+	errs := make([]error, len(m.clients))
+	var wg sync.WaitGroup
+	for i, c := range m.clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			errs[i] = c.Insert(input)
+		}(i, c)
+	}
+	wg.Wait()
+
+	failed := make(map[string]error)
+	for i, err := range errs {
+		if err != nil {
+			failed[m.clients[i].config.Name] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &MultiInsertError{Failed: failed}
+	}
+
+	return nil
+}
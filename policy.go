@@ -0,0 +1,72 @@
+package sqs
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+type policyStatement struct {
+	Sid       string
+	Effect    string
+	Principal map[string]string
+	Action    string
+	Resource  string
+	Condition map[string]map[string]string
+}
+
+// AllowSNSTopic sets the access policy of the queue to allow the SNS topic with the given ARN to
+// send messages to it, so the queue can be subscribed to the topic. This replaces any existing
+// policy on the queue.
+func (c *Client) AllowSNSTopic(topicARN string) error {
+	attrs, err := c.getAttributes(sqs.QueueAttributeNameQueueArn)
+	if err != nil {
+		return err
+	}
+
+	policy, err := snsPolicy(attrs[sqs.QueueAttributeNameQueueArn], topicARN)
+	if err != nil {
+		return err
+	}
+
+	return c.setAttributes(map[string]string{sqs.QueueAttributeNamePolicy: policy})
+}
+
+// snsPolicy returns a policy allowing the topic to send messages to the queue.
+func snsPolicy(queueARN, topicARN string) (string, error) {
+	doc := policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{{
+			Sid:       "AllowSNSTopic",
+			Effect:    "Allow",
+			Principal: map[string]string{"Service": "sns.amazonaws.com"},
+			Action:    "sqs:SendMessage",
+			Resource:  queueARN,
+			Condition: map[string]map[string]string{
+				"ArnEquals": {"aws:SourceArn": topicARN},
+			},
+		}},
+	}
+
+	b, err := json.Marshal(doc)
+	return string(b), err
+}
+
+// setAttributes sets attributes of the queue.
+func (c *Client) setAttributes(attrs map[string]string) error {
+	request := &sqs.SetQueueAttributesInput{
+		Attributes: aws.StringMap(attrs),
+		QueueUrl:   c.currentURL(),
+	}
+
+	return c.withURL(&request.QueueUrl, func() error {
+		_, err := c.client.SetQueueAttributes(request)
+		return err
+	})
+}
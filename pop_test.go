@@ -0,0 +1,118 @@
+package sqs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// slowMock is a MockAPIService whose receives and deletes each take a while, like requests that the
+// AWS SDK keeps retrying.
+type slowMock struct {
+	*MockAPIService
+	receiveDelay time.Duration
+	deleteDelay  time.Duration
+}
+
+// wait waits for d, failing like the AWS SDK if ctx is done first.
+func (m slowMock) wait(ctx aws.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+	}
+}
+
+func (m slowMock) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if err := m.wait(ctx, m.receiveDelay); err != nil {
+		return nil, err
+	}
+
+	return m.MockAPIService.ReceiveMessageWithContext(ctx, input, opts...)
+}
+
+func (m slowMock) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	if err := m.wait(ctx, m.deleteDelay); err != nil {
+		return nil, err
+	}
+
+	return m.MockAPIService.DeleteMessageWithContext(ctx, input, opts...)
+}
+
+func TestPopDeadline(t *testing.T) {
+	tests := []struct {
+		name         string
+		bodies       []string
+		receiveDelay time.Duration
+		deleteDelay  time.Duration
+		popped       bool
+		err          error
+	}{
+		{name: "popped", bodies: []string{"body"}, popped: true},
+		{name: "empty queue", err: nil},
+		{name: "slow receive", bodies: []string{"body"}, receiveDelay: time.Minute, err: context.DeadlineExceeded},
+		{name: "slow delete", bodies: []string{"body"}, deleteDelay: time.Minute, err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig("orders")
+			config.PopDeadline = 50 * time.Millisecond
+			mock := slowMock{NewMockAPIService(tt.bodies...), tt.receiveDelay, tt.deleteDelay}
+			c, err := newClient(config, mock)
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			start := time.Now()
+			msg, err := c.Pop()
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Pop took %v, want it bounded by the deadline", elapsed)
+			}
+			if err != tt.err {
+				t.Errorf("Pop() error = %v, want %v", err, tt.err)
+			}
+			if (msg != nil) != tt.popped {
+				t.Errorf("Pop() = %v, want a message %v", msg, tt.popped)
+			}
+		})
+	}
+}
+
+func TestPopDeadlineHonorsContext(t *testing.T) {
+	config := testConfig("orders")
+	config.PopDeadline = time.Minute
+	c, err := newClient(config, slowMock{MockAPIService: NewMockAPIService("body"), receiveDelay: time.Minute})
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.PopContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("PopContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestEmptyReceiveDelayHonorsContext(t *testing.T) {
+	config := testConfig("orders")
+	config.EmptyReceiveDelay = time.Minute
+	c := newTestClient(t, config, NewMockAPIService("body"))
+	c.emptyThrottle.record(true, c.now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.PopContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("PopContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("PopContext took %v, want the delay cut short by the context", elapsed)
+	}
+}
@@ -0,0 +1,171 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Handler processes a single message. The message is deleted if the handler returns nil, otherwise
+// it becomes visible again once its visibility timeout expires and will be retried.
+type Handler func(ctx context.Context, msg *sqs.Message) error
+
+// ProcessOptions configures Process.
+type ProcessOptions struct {
+	ConsumeOptions
+	// Workers is the number of messages handled concurrently. Defaults to 1.
+	Workers int
+	// SafetyMargin is how long before a message's visibility timeout expires its handler's context
+	// is canceled, leaving time to stop work before the message can be received by another consumer.
+	// Defaults to a tenth of the visibility timeout remaining when the handler is called.
+	SafetyMargin time.Duration
+	// OnError, if set, is called with errors from receiving, handling and deleting messages.
+	OnError func(error)
+	// ReleaseFraction, if between 0 and 1, releases a received message back to the queue, by setting
+	// its visibility timeout to 0, if no worker is free to take it before this fraction of its
+	// visibility timeout has elapsed. While all workers are busy this lets another consumer process
+	// the message straight away instead of once its visibility timeout expires.
+	ReleaseFraction float64
+}
+
+// Process receives messages from the queue and passes each to h from a pool of workers until ctx
+// is canceled. Each handler is given a context that is canceled SafetyMargin before the visibility
+// timeout of its message expires, so handlers should stop work once it is done. Process returns
+// once all workers have finished.
+func (c *Client) Process(ctx context.Context, opts ProcessOptions, h Handler) {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	onError := opts.OnError
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	msgs, errs := c.Consume(ctx, opts.ConsumeOptions)
+	go func() {
+		for err := range errs {
+			onError(err)
+		}
+	}()
+
+	if opts.ReleaseFraction > 0 && opts.ReleaseFraction < 1 {
+		msgs = c.dispatch(ctx, msgs, opts, onError)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				if err := c.handle(ctx, msg, h, opts.SafetyMargin, opts.Deleter); err != nil {
+					onError(err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// HandleNext receives a single message and passes it to h, deleting the message only if h returns
+// nil. This sits between Peek, which leaves deletion to the caller, and Pop, which deletes the
+// message before the caller has processed it: if h fails the message stays in the queue and is
+// received again after its visibility timeout. h's context is set up as in Process with the default
+// safety margin, and ctx also bounds the receive. HandleNext reports whether a message was
+// received, and returns the error from h or from deleting the message.
+func (c *Client) HandleNext(ctx context.Context, h Handler) (bool, error) {
+	msg, err := c.peek(ctx)
+	if err != nil || msg == nil {
+		return false, err
+	}
+
+	return true, c.handle(ctx, msg, h, 0, nil)
+}
+
+// dispatch passes messages from msgs to the returned channel, releasing any that are not taken
+// before opts.ReleaseFraction of their visibility timeout has elapsed, or before ctx is canceled.
+func (c *Client) dispatch(ctx context.Context, msgs <-chan *sqs.Message, opts ProcessOptions, onError func(error)) <-chan *sqs.Message {
+	timeout := c.visibilityTimeout()
+	if opts.VisibilityTimeoutSeconds > 0 {
+		timeout = time.Duration(opts.VisibilityTimeoutSeconds) * time.Second
+	}
+	hold := time.Duration(float64(timeout) * (1 - opts.ReleaseFraction))
+
+	out := make(chan *sqs.Message)
+	go func() {
+		defer close(out)
+		for msg := range msgs {
+			if c.offer(ctx, out, msg, c.releaseTime(msg, hold)) {
+				continue
+			}
+
+			if err := c.ReleaseMessage(msg); err != nil {
+				onError(err)
+			}
+		}
+	}()
+
+	return out
+}
+
+// releaseTime returns when a message that has not been taken by a worker should be released: hold
+// before its visibility timeout expires. The zero time means never.
+func (c *Client) releaseTime(msg *sqs.Message, hold time.Duration) time.Time {
+	expires, ok := c.handles.get(msg)
+	if !ok {
+		return time.Time{}
+	}
+
+	return expires.Add(-hold)
+}
+
+// offer sends msg on out, returning false if release passes or ctx is canceled first.
+func (c *Client) offer(ctx context.Context, out chan<- *sqs.Message, msg *sqs.Message, release time.Time) bool {
+	var expired <-chan time.Time
+	if !release.IsZero() {
+		t := time.NewTimer(release.Sub(c.now()))
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case out <- msg:
+		return true
+	case <-expired:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// handle calls h with a context that expires before the message's visibility timeout and deletes
+// the message if h succeeds, through d if it is set.
+func (c *Client) handle(ctx context.Context, msg *sqs.Message, h Handler, margin time.Duration, d *BatchDeleter) error {
+	ctx, cancel := c.messageContext(ctx, msg, margin)
+	defer cancel()
+
+	if err := h(ctx, msg); err != nil {
+		return err
+	}
+
+	return c.deleteWith(d, msg)
+}
+
+// messageContext returns a context that is canceled margin before the visibility timeout of msg
+// expires. If margin is 0 a tenth of the remaining visibility timeout is used.
+func (c *Client) messageContext(ctx context.Context, msg *sqs.Message, margin time.Duration) (context.Context, context.CancelFunc) {
+	expires, ok := c.handles.get(msg)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	if margin <= 0 {
+		margin = expires.Sub(c.now()) / 10
+	}
+
+	return context.WithTimeout(ctx, expires.Add(-margin).Sub(c.now()))
+}
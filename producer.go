@@ -0,0 +1,305 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxBatchBytes is the largest combined body size SQS accepts in a single batch request.
+const maxBatchBytes = 256 * 1024
+
+// Metrics reported for each batch a BufferedProducer sends, including those of the background
+// batcher used with Config.InsertBufferSize. The average batch size is batched_messages divided by
+// batches_flushed; batch_fill is the fraction of the batch count limit used by the latest batch.
+const (
+	batchesFlushedMetric  = "batches_flushed"
+	batchedMessagesMetric = "batched_messages"
+	batchFillMetric       = "batch_fill"
+)
+
+// BufferedProducer accumulates messages and inserts them into the queue in batches. A batch is sent
+// when it holds 10 messages, when adding a message would exceed the batch size limit, or when the
+// flush interval elapses. Messages that fail to send because of throttling or an outage are kept and
+// retried on the next flush; messages that could never be sent are dropped and reported in a
+// *DroppedError, so they do not hold up the rest of the buffer.
+type BufferedProducer struct {
+	client *Client
+	// maxCount and maxBytes limit the number of messages and the combined body size of a batch.
+	maxCount int
+	maxBytes int
+
+	mu      sync.Mutex
+	pending []string
+	err     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// DroppedError is returned by a BufferedProducer when it discards buffered messages that failed in
+// a way that retrying would not fix, such as a request AWS rejected as invalid.
+type DroppedError struct {
+	// Bodies are the discarded messages, which can be inspected or inserted elsewhere.
+	Bodies []string
+	// Err is the error the first of them failed with.
+	Err error
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("sqs: dropped %d buffered messages: %v", len(e.Bodies), e.Err)
+}
+
+// Unwrap returns the error the messages failed with.
+func (e *DroppedError) Unwrap() error {
+	return e.Err
+}
+
+// NewBufferedProducer returns a BufferedProducer that inserts into the queue. If flushInterval is
+// greater than 0, buffered messages are also flushed in the background at that interval. Errors from
+// background flushes are returned by the next call to Add, Flush or Close.
+func (c *Client) NewBufferedProducer(flushInterval time.Duration) *BufferedProducer {
+	return newBufferedProducer(c, flushInterval, MaxBatchSize, maxBatchBytes)
+}
+
+// newBufferedProducer returns a BufferedProducer whose batches hold at most maxCount messages and
+// maxBytes of bodies.
+func newBufferedProducer(c *Client, flushInterval time.Duration, maxCount, maxBytes int) *BufferedProducer {
+	p := &BufferedProducer{
+		client:   c,
+		maxCount: maxCount,
+		maxBytes: maxBytes,
+		done:     make(chan struct{}),
+	}
+
+	if flushInterval > 0 {
+		p.wg.Add(1)
+		go p.flushEvery(flushInterval)
+	}
+
+	return p
+}
+
+// Add buffers body to be inserted into the queue, flushing first if the current batch is full. The
+// body is checked as Insert would, so an invalid body such as one over MaxMessageSize is rejected
+// here rather than when it is sent. Add only returns an error if body was not buffered, so it can
+// be retried: the error may be from the check, or from an earlier flush. An error flushing the
+// batch that body completes is returned by the next call to Add, Flush or Close.
+func (p *BufferedProducer) Add(body string) error {
+	if _, _, err := p.client.prepareMessage(body, nil); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.takeErr(); err != nil {
+		return err
+	}
+
+	if p.overflows(body) {
+		if err := p.flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.push(body); err != nil {
+		p.err = err
+	}
+
+	return nil
+}
+
+// addChecked buffers body, which has already been checked as in Add, flushing first if the current
+// batch is full. Unlike Add it always buffers body, and returns any error, including one from an
+// earlier flush, so that the asyncInserter loses no messages.
+func (p *BufferedProducer) addChecked(body string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.takeErr()
+	if p.overflows(body) {
+		if ferr := p.flush(); err == nil {
+			err = ferr
+		}
+	}
+
+	if perr := p.push(body); err == nil {
+		err = perr
+	}
+
+	return err
+}
+
+// overflows reports whether adding body would exceed the byte limit of the current batch. p.mu
+// must be held.
+func (p *BufferedProducer) overflows(body string) bool {
+	return len(p.pending) > 0 && batchBytes(p.pending)+len(body) > p.maxBytes
+}
+
+// push buffers body and flushes if that fills the batch, returning the error from the flush. p.mu
+// must be held.
+func (p *BufferedProducer) push(body string) error {
+	p.pending = append(p.pending, body)
+	if len(p.pending) < p.maxCount {
+		return nil
+	}
+
+	return p.flush()
+}
+
+// Flush inserts all buffered messages into the queue. Messages that fail because of throttling or an
+// outage remain buffered and are retried by the next flush; others are dropped and returned in a
+// *DroppedError.
+func (p *BufferedProducer) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.takeErr(); err != nil {
+		return err
+	}
+
+	return p.flush()
+}
+
+// Unflushed returns the messages that have been added but not yet inserted into the queue.
+func (p *BufferedProducer) Unflushed() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.pending...)
+}
+
+// Close stops background flushing and flushes any buffered messages. If the final flush fails the
+// unsent messages can be retrieved with Unflushed. Calling Close again flushes again.
+func (p *BufferedProducer) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	p.wg.Wait()
+	return p.Flush()
+}
+
+// flushEvery flushes the buffer every interval until the producer is closed.
+func (p *BufferedProducer) flushEvery(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if err := p.flush(); err != nil && p.err == nil {
+				p.err = err
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// flush sends the buffered messages in batches. Batches and entries that fail permanently are
+// dropped and the rest of the buffer is still sent; the first retryable failure stops the flush and
+// leaves the messages not yet sent buffered. p.mu must be held.
+func (p *BufferedProducer) flush() error {
+	metrics := p.client.metrics()
+	var dropped *DroppedError
+	drop := func(bodies []string, err error) {
+		if dropped == nil {
+			dropped = &DroppedError{Err: err}
+		}
+		dropped.Bodies = append(dropped.Bodies, bodies...)
+	}
+
+	var retryErr error
+	for len(p.pending) > 0 && retryErr == nil {
+		n := batchLen(p.pending, p.maxCount, p.maxBytes)
+		metrics.Count(batchesFlushedMetric, 1)
+		metrics.Count(batchedMessagesMetric, n)
+		metrics.Gauge(batchFillMetric, float64(n)/float64(p.maxCount))
+
+		results, err := p.client.InsertBatchResults(p.pending[:n])
+		if err != nil {
+			if isRetryableSend(err) {
+				retryErr = err
+				break
+			}
+
+			drop(p.pending[:n], err)
+			p.pending = p.pending[n:]
+			continue
+		}
+
+		var retry []string
+		for i, r := range results {
+			switch {
+			case r.Err == nil:
+			case isRetryableEntry(r.Err):
+				retry = append(retry, p.pending[i])
+			default:
+				drop([]string{p.pending[i]}, r.Err)
+			}
+		}
+
+		if len(retry) > 0 {
+			retryErr = newInsertBatchError(results)
+		}
+		p.pending = append(retry, p.pending[n:]...)
+	}
+
+	if len(p.pending) == 0 {
+		p.pending = nil
+	}
+
+	if dropped != nil {
+		return dropped
+	}
+
+	return retryErr
+}
+
+// isRetryableSend reports whether a batch request that failed as a whole with err may succeed if
+// sent again later.
+func isRetryableSend(err error) bool {
+	return isOutage(err) || errors.Is(err, ErrBreakerOpen)
+}
+
+// takeErr returns and clears the error from the last background flush. p.mu must be held.
+func (p *BufferedProducer) takeErr() error {
+	err := p.err
+	p.err = nil
+	return err
+}
+
+// nextBatchLen returns how many of bodies, starting from the first, fit in a single batch request.
+func nextBatchLen(bodies []string) int {
+	return batchLen(bodies, MaxBatchSize, maxBatchBytes)
+}
+
+// batchLen returns how many of bodies, starting from the first, fit in a batch of at most maxCount
+// messages and maxBytes of bodies. The first body is always included.
+func batchLen(bodies []string, maxCount, maxBytes int) int {
+	n, size := 0, 0
+	for n < len(bodies) && n < maxCount {
+		size += len(bodies[n])
+		if n > 0 && size > maxBytes {
+			break
+		}
+		n++
+	}
+
+	return n
+}
+
+// batchBytes returns the combined size of bodies.
+func batchBytes(bodies []string) int {
+	size := 0
+	for _, b := range bodies {
+		size += len(b)
+	}
+
+	return size
+}
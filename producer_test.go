@@ -0,0 +1,173 @@
+package sqs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// rejectingMock is a MockAPIService that rejects the batch entries whose body is "bad" as invalid,
+// a failure that retrying would not fix.
+type rejectingMock struct {
+	*MockAPIService
+}
+
+func (m rejectingMock) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	accepted := *input
+	accepted.Entries = nil
+	var failed []*sqs.BatchResultErrorEntry
+	for _, e := range input.Entries {
+		if aws.StringValue(e.MessageBody) != "bad" {
+			accepted.Entries = append(accepted.Entries, e)
+			continue
+		}
+
+		failed = append(failed, &sqs.BatchResultErrorEntry{
+			Code:        aws.String("InvalidMessageContents"),
+			Id:          e.Id,
+			Message:     aws.String("invalid"),
+			SenderFault: aws.Bool(true),
+		})
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	if len(accepted.Entries) > 0 {
+		var err error
+		if out, err = m.MockAPIService.SendMessageBatchWithContext(ctx, &accepted, opts...); err != nil {
+			return nil, err
+		}
+	}
+	out.Failed = append(out.Failed, failed...)
+
+	return out, nil
+}
+
+func TestBufferedProducerFlush(t *testing.T) {
+	errThrottled := awserr.New("ThrottlingException", "Rate exceeded", nil)
+	errDenied := awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), 403, "id")
+	tests := []struct {
+		name       string
+		bodies     []string
+		fail       error // the error of the next SendMessageBatch request
+		failEntry  int   // the number of entries rejected as throttled
+		sent       []string
+		unflushed  []string
+		dropped    []string
+		retryLater bool // whether the flush fails with an error that leaves messages buffered
+	}{
+		{name: "sent", bodies: []string{"a", "b"}, sent: []string{"a", "b"}},
+		{
+			name:       "throttled request",
+			bodies:     []string{"a", "b"},
+			fail:       errThrottled,
+			unflushed:  []string{"a", "b"},
+			retryLater: true,
+		},
+		{name: "rejected request", bodies: []string{"a", "b"}, fail: errDenied, dropped: []string{"a", "b"}},
+		{
+			name:       "throttled entry",
+			bodies:     []string{"a", "b"},
+			failEntry:  1,
+			sent:       []string{"b"},
+			unflushed:  []string{"a"},
+			retryLater: true,
+		},
+		{name: "rejected entry", bodies: []string{"a", "bad", "b"}, sent: []string{"a", "b"}, dropped: []string{"bad"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			c, err := newClient(testConfig("orders"), rejectingMock{mock})
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			p := c.NewBufferedProducer(0)
+			for _, body := range tt.bodies {
+				if err := p.Add(body); err != nil {
+					t.Fatalf("Add(%q): %v", body, err)
+				}
+			}
+
+			if tt.fail != nil {
+				mock.FailNext("SendMessageBatch", tt.fail)
+			}
+			mock.FailBatchEntries(tt.failEntry)
+			err = p.Flush()
+
+			var dropped *DroppedError
+			if errors.As(err, &dropped) {
+				if !reflect.DeepEqual(dropped.Bodies, tt.dropped) {
+					t.Errorf("dropped %q, want %q", dropped.Bodies, tt.dropped)
+				}
+			} else if tt.dropped != nil {
+				t.Errorf("Flush() = %v, want a *DroppedError", err)
+			}
+			if (err != nil && dropped == nil) != tt.retryLater {
+				t.Errorf("Flush() = %v, want a retryable error %v", err, tt.retryLater)
+			}
+
+			if got := mock.Sent(); !reflect.DeepEqual(got, tt.sent) {
+				t.Errorf("sent %q, want %q", got, tt.sent)
+			}
+			if got := p.Unflushed(); !reflect.DeepEqual(got, tt.unflushed) {
+				t.Errorf("Unflushed() = %q, want %q", got, tt.unflushed)
+			}
+
+			if err := p.Close(); err != nil {
+				t.Errorf("Close() = %v", err)
+			}
+			if n := len(mock.Sent()); n != len(tt.sent)+len(tt.unflushed) {
+				t.Errorf("%d messages sent after Close, want the retried messages sent", n)
+			}
+		})
+	}
+}
+
+func TestBufferedProducerAdd(t *testing.T) {
+	mock := NewMockAPIService()
+	c := newTestClient(t, testConfig("orders"), mock)
+	p := newBufferedProducer(c, 0, 2, maxBatchBytes)
+
+	if err := p.Add(strings.Repeat("x", MaxMessageSize+1)); err != ErrMessageTooLarge {
+		t.Errorf("Add() of an oversized body = %v, want %v", err, ErrMessageTooLarge)
+	}
+	if got := p.Unflushed(); len(got) != 0 {
+		t.Errorf("the oversized body was buffered")
+	}
+
+	// The batch completed by "b" fails to send, which is reported by the next Add without
+	// buffering its body, so that it can be retried.
+	mock.FailNext("SendMessageBatch", awserr.New("ThrottlingException", "Rate exceeded", nil))
+	for _, body := range []string{"a", "b"} {
+		if err := p.Add(body); err != nil {
+			t.Fatalf("Add(%q): %v", body, err)
+		}
+	}
+	if err := p.Add("c"); err == nil {
+		t.Fatal("Add() did not report the failed flush")
+	}
+	if got := p.Unflushed(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Unflushed() = %q, want the failed batch without the rejected body", got)
+	}
+	if err := p.Add("c"); err != nil {
+		t.Fatalf("Add() retry: %v", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if got := mock.Sent(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("sent %q, want each body once", got)
+	}
+}
@@ -0,0 +1,205 @@
+package sqs
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// fifoSuffix is the suffix AWS requires FIFO queue names to end with.
+const fifoSuffix = ".fifo"
+
+// QueueManager creates, finds, lists and deletes the queues of one region. The package level
+// functions such as CreateQueue use a QueueManager for the region they are given; use
+// NewMockQueueManager to run the same operations against a MockAPIService in tests.
+type QueueManager struct {
+	service queueClient
+}
+
+// NewQueueManager returns a QueueManager for the given region. Managers for the same region share
+// an SQS client.
+func NewQueueManager(region string) (*QueueManager, error) {
+	service, err := getService(region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueueManager{service: service}, nil
+}
+
+// NewMockQueueManager returns a QueueManager backed by mock instead of AWS, for use in tests.
+func NewMockQueueManager(mock *MockAPIService) *QueueManager {
+	return &QueueManager{service: mock}
+}
+
+// CreateQueue creates a queue if it does not already exist. Names ending in .fifo create a FIFO
+// queue.
+func (m *QueueManager) CreateQueue(ctx context.Context, name string) error {
+	req := &sqs.CreateQueueInput{QueueName: &name}
+	if strings.HasSuffix(name, fifoSuffix) {
+		req.Attributes = map[string]*string{sqs.QueueAttributeNameFifoQueue: aws.String("true")}
+	}
+
+	_, err := m.service.CreateQueueWithContext(ctx, req)
+	return err
+}
+
+// QueueExists reports whether a queue exists. If name does not end in .fifo and no standard queue
+// has that name, the FIFO queue name+".fifo" is also checked, so both "orders" and "orders.fifo"
+// find a FIFO queue named "orders.fifo".
+func (m *QueueManager) QueueExists(ctx context.Context, name string) (bool, error) {
+	_, err := resolveQueueURL(ctx, m.service, name)
+	if isQueueNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// DeleteQueue deletes the queue with exactly the given name. Unlike QueueExists it does not fall
+// back to the FIFO queue name+".fifo", so deleting a standard queue that does not exist never
+// deletes a FIFO queue instead.
+func (m *QueueManager) DeleteQueue(ctx context.Context, name string) error {
+	url, err := queueURLContext(ctx, name, m.service)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.service.DeleteQueueWithContext(ctx, &sqs.DeleteQueueInput{QueueUrl: &url})
+	return err
+}
+
+// ListQueues returns the names of the queues whose names start with prefix, or all queues if prefix
+// is empty. SQS returns at most 1000 queues.
+func (m *QueueManager) ListQueues(ctx context.Context, prefix string) ([]string, error) {
+	req := &sqs.ListQueuesInput{}
+	if prefix != "" {
+		req.QueueNamePrefix = &prefix
+	}
+
+	resp, err := m.service.ListQueuesWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(resp.QueueUrls))
+	for _, url := range resp.QueueUrls {
+		names = append(names, queueName(aws.StringValue(url)))
+	}
+
+	return names, nil
+}
+
+// CreateQueue creates a queue in the given region if it does not already exist. Names ending in
+// .fifo create a FIFO queue.
+func CreateQueue(region, name string) error {
+	return CreateQueueContext(context.Background(), region, name)
+}
+
+// CreateQueueContext is like CreateQueue but gives up when ctx is done.
+func CreateQueueContext(ctx context.Context, region, name string) error {
+	m, err := NewQueueManager(region)
+	if err != nil {
+		return err
+	}
+
+	return m.CreateQueue(ctx, name)
+}
+
+// QueueExists reports whether a queue exists in the given region. The name is resolved as in
+// QueueManager.QueueExists, so both "orders" and "orders.fifo" find a FIFO queue named
+// "orders.fifo".
+func QueueExists(region, name string) (bool, error) {
+	return QueueExistsContext(context.Background(), region, name)
+}
+
+// QueueExistsContext is like QueueExists but gives up when ctx is done.
+func QueueExistsContext(ctx context.Context, region, name string) (bool, error) {
+	m, err := NewQueueManager(region)
+	if err != nil {
+		return false, err
+	}
+
+	return m.QueueExists(ctx, name)
+}
+
+// DeleteQueue deletes the queue with exactly the given name in the given region. Unlike
+// QueueExists it never falls back to a FIFO queue name.
+func DeleteQueue(region, name string) error {
+	return DeleteQueueContext(context.Background(), region, name)
+}
+
+// DeleteQueueContext is like DeleteQueue but gives up when ctx is done.
+func DeleteQueueContext(ctx context.Context, region, name string) error {
+	m, err := NewQueueManager(region)
+	if err != nil {
+		return err
+	}
+
+	return m.DeleteQueue(ctx, name)
+}
+
+// ListQueues returns the names of the queues in the given region whose names start with prefix,
+// or all queues if prefix is empty. SQS returns at most 1000 queues.
+func ListQueues(region, prefix string) ([]string, error) {
+	return ListQueuesContext(context.Background(), region, prefix)
+}
+
+// ListQueuesContext is like ListQueues but gives up when ctx is done.
+func ListQueuesContext(ctx context.Context, region, prefix string) ([]string, error) {
+	m, err := NewQueueManager(region)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ListQueues(ctx, prefix)
+}
+
+// queueName returns the name of a queue from its URL, which ends in the account ID and name.
+func queueName(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// resolveQueueURL returns the URL of the named queue, falling back to the FIFO queue of the same
+// base name if name has no .fifo suffix and no such standard queue exists.
+func resolveQueueURL(ctx context.Context, service queueClient, name string) (string, error) {
+	url, err := queueURLContext(ctx, name, service)
+	if isQueueNotExist(err) && !strings.HasSuffix(name, fifoSuffix) {
+		return queueURLContext(ctx, name+fifoSuffix, service)
+	}
+
+	return url, err
+}
+
+// services caches the SQS client for each region used by the queue management functions, so
+// repeated calls share a session instead of loading the AWS configuration each time.
+var services = struct {
+	sync.Mutex
+	byRegion map[string]queueClient
+}{byRegion: make(map[string]queueClient)}
+
+// getService returns the SQS client for the given region, creating it on first use.
+func getService(region string) (queueClient, error) {
+	services.Lock()
+	defer services.Unlock()
+
+	if service, ok := services.byRegion[region]; ok {
+		return service, nil
+	}
+
+	s, err := newSession(Config{Region: region})
+	if err != nil {
+		return nil, err
+	}
+
+	service := sqs.New(s)
+	services.byRegion[region] = service
+	return service, nil
+}
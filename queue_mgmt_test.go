@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQueueManagerQueueExists(t *testing.T) {
+	errDenied := errors.New("access denied")
+	tests := []struct {
+		name   string
+		queue  string
+		errors []error // returned by successive GetQueueUrl calls
+		want   bool
+		err    error
+	}{
+		{name: "exists", queue: "orders", want: true},
+		{name: "FIFO fallback", queue: "orders", errors: []error{errQueueNotExist}, want: true},
+		{name: "missing", queue: "orders", errors: []error{errQueueNotExist, errQueueNotExist}},
+		{name: "missing FIFO queue", queue: "orders.fifo", errors: []error{errQueueNotExist}},
+		{name: "other error", queue: "orders", errors: []error{errDenied}, err: errDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			for _, err := range tt.errors {
+				mock.FailNext("GetQueueUrl", err)
+			}
+
+			got, err := NewMockQueueManager(mock).QueueExists(context.Background(), tt.queue)
+			if got != tt.want || err != tt.err {
+				t.Errorf("QueueExists(%q) = %v, %v, want %v, %v", tt.queue, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestQueueManagerDeleteQueueExactName(t *testing.T) {
+	mock := NewMockAPIService("body")
+	mock.FailNext("GetQueueUrl", errQueueNotExist)
+
+	if err := NewMockQueueManager(mock).DeleteQueue(context.Background(), "orders"); !isQueueNotExist(err) {
+		t.Fatalf("DeleteQueue() = %v, want QueueDoesNotExist", err)
+	}
+
+	if n := len(mock.Remaining()); n != 1 {
+		t.Errorf("DeleteQueue fell back to another queue: %d messages remain, want 1", n)
+	}
+}
+
+func TestQueueManagerListQueues(t *testing.T) {
+	m := NewMockQueueManager(NewMockAPIService())
+	if err := m.CreateQueue(context.Background(), "orders"); err != nil {
+		t.Fatalf("CreateQueue: %v", err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"orders"}},
+		{prefix: "ord", want: []string{"orders"}},
+		{prefix: "payments", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got, err := m.ListQueues(context.Background(), tt.prefix)
+		if err != nil {
+			t.Fatalf("ListQueues(%q): %v", tt.prefix, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListQueues(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package sqs
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrNoReplayBuffer is returned by Replay when Config.ReplayBufferSize is not set.
+var ErrNoReplayBuffer = errors.New("sqs: replay requires Config.ReplayBufferSize")
+
+// replayMessage is a received message retained by a replayBuffer.
+type replayMessage struct {
+	body    string
+	attrs   map[string]*sqs.MessageAttributeValue
+	groupID string
+}
+
+// replayBuffer retains the most recently delivered messages so that they can be inserted again. It
+// is a ring of size messages: once full, each message added overwrites the oldest.
+type replayBuffer struct {
+	size int
+
+	mu    sync.Mutex
+	msgs  []replayMessage
+	start int
+	n     int
+}
+
+// push adds msg to the ring, overwriting the oldest message if it is full. b.mu must be held.
+func (b *replayBuffer) push(msg replayMessage) {
+	if b.msgs == nil {
+		b.msgs = make([]replayMessage, b.size)
+	}
+
+	if b.n < b.size {
+		b.msgs[(b.start+b.n)%b.size] = msg
+		b.n++
+		return
+	}
+
+	b.msgs[b.start] = msg
+	b.start = (b.start + 1) % b.size
+}
+
+// add retains msgs, forgetting the oldest messages beyond the size of the buffer.
+func (b *replayBuffer) add(msgs []*sqs.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, msg := range msgs {
+		attrs := make(map[string]*sqs.MessageAttributeValue, len(msg.MessageAttributes))
+		for k, v := range msg.MessageAttributes {
+			attrs[k] = v
+		}
+
+		groupID, _ := systemAttribute(msg, sqs.MessageSystemAttributeNameMessageGroupId)
+		b.push(replayMessage{
+			body:    aws.StringValue(msg.Body),
+			attrs:   attrs,
+			groupID: groupID,
+		})
+	}
+}
+
+// take removes and returns the retained messages, oldest first.
+func (b *replayBuffer) take() []replayMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.drain()
+}
+
+// drain empties the ring and returns its messages, oldest first. b.mu must be held.
+func (b *replayBuffer) drain() []replayMessage {
+	msgs := make([]replayMessage, b.n)
+	for i := range msgs {
+		msgs[i] = b.msgs[(b.start+i)%b.size]
+		b.msgs[(b.start+i)%b.size] = replayMessage{}
+	}
+
+	b.start, b.n = 0, 0
+	return msgs
+}
+
+// restore puts back messages returned by take that were not replayed, ahead of any received since,
+// forgetting the oldest if the buffer overflows.
+func (b *replayBuffer) restore(msgs []replayMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	since := b.drain()
+	for _, msg := range append(append([]replayMessage(nil), msgs...), since...) {
+		b.push(msg)
+	}
+}
+
+// retain adds msgs, which are being delivered to the caller, to the replay buffer if there is one.
+func (c *Client) retain(msgs []*sqs.Message) {
+	if c.replay != nil {
+		c.replay.add(msgs)
+	}
+}
+
+// Replay inserts the messages retained by Config.ReplayBufferSize into the queue again, oldest
+// first, with their original message attributes, and returns how many were inserted. It is meant
+// for recovering from a bug in a consumer after it is fixed, without a dead letter queue. Replayed
+// messages are removed from the buffer; if an insert fails, Replay stops and the messages not yet
+// inserted stay in the buffer so Replay can be called again.
+//
+// Messages are retained when they are delivered by Peek, PeekBatch, Receive, Pop, PopBatch,
+// ReceiveJSON, Consume or Process, whether or not they were processed successfully. Messages that
+// fail Config.BodyValidator or that ReceiveJSON cannot decode are not retained, and neither are
+// messages only looked at by Browse, IsEmpty, OldestMessageAge, DeleteOlderThan, DeleteMatching,
+// DrainAll or ArchiveToS3.
+// On a FIFO queue they are inserted into their original message group with a new deduplication ID,
+// so the group must have been received with its system attributes; messages received with
+// Config.MinimalReceive cannot be replayed to a FIFO queue.
+func (c *Client) Replay() (int, error) {
+	if c.replay == nil {
+		return 0, ErrNoReplayBuffer
+	}
+
+	msgs := c.replay.take()
+	for i, msg := range msgs {
+		body := msg.body
+		request := &sqs.SendMessageInput{
+			MessageAttributes: msg.attrs,
+			MessageBody:       &body,
+			QueueUrl:          c.currentURL(),
+		}
+		if c.config.isFIFO() {
+			if msg.groupID == "" {
+				c.replay.restore(msgs[i:])
+				return i, ErrMissingGroupID
+			}
+
+			request.MessageGroupId = aws.String(msg.groupID)
+			request.MessageDeduplicationId = randomID()
+		}
+
+		if err := c.sendMessage(request); err != nil {
+			c.replay.restore(msgs[i:])
+			return i, err
+		}
+	}
+
+	return len(msgs), nil
+}
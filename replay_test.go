@@ -0,0 +1,61 @@
+package sqs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestReplayRetainsDeliveredMessages(t *testing.T) {
+	valid, undecodable := `{"n":1}`, `{"n":`
+	tests := []struct {
+		name     string
+		receive  func(c *Client)
+		replayed []string
+	}{
+		{name: "PeekBatch", receive: func(c *Client) { c.PeekBatch() }, replayed: []string{valid, undecodable}},
+		{name: "Receive", receive: func(c *Client) { c.Receive(MaxBatchSize) }, replayed: []string{valid, undecodable}},
+		{name: "PopBatch", receive: func(c *Client) { c.PopBatch() }, replayed: []string{valid, undecodable}},
+		{
+			name: "Peek",
+			receive: func(c *Client) {
+				for i := 0; i < 3; i++ {
+					c.Peek()
+				}
+			},
+			replayed: []string{valid, undecodable},
+		},
+		{
+			name: "ReceiveJSON",
+			receive: func(c *Client) {
+				c.ReceiveJSON(context.Background(), MaxBatchSize, func() interface{} { return new(map[string]int) })
+			},
+			replayed: []string{valid},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, quarantine := range []string{"", "orders-quarantine"} {
+			t.Run(tt.name+" "+quarantine, func(t *testing.T) {
+				// Without a quarantine queue the invalid messages are left in flight, and with one they
+				// are copied there; either way they must not be replayed.
+				mock := NewMockAPIService(valid, "bad", undecodable)
+				config := testConfig("orders")
+				config.BodyValidator = rejectBad
+				config.QuarantineQueue = quarantine
+				config.ReplayBufferSize = MaxBatchSize
+				c := newTestClient(t, config, mock)
+
+				tt.receive(c)
+				before := len(mock.Sent())
+				if _, err := c.Replay(); err != nil {
+					t.Fatalf("Replay: %v", err)
+				}
+
+				if got := mock.Sent()[before:]; !reflect.DeepEqual(got, tt.replayed) {
+					t.Errorf("replayed %q, want %q", got, tt.replayed)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DeleteOlderThan deletes messages that were sent to the queue more than age ago and returns the
+// number deleted. Newer messages are made visible again immediately.
+//
+// SQS cannot delete by age, so the queue is scanned by receiving batches of messages until a receive
+// returns no messages that have not already been seen. SQS samples a subset of its servers on each
+// receive, so on a large or busy queue some old messages may not be seen, and messages in flight
+// with other consumers are never seen. Messages without a sent time are left in the queue.
+func (c *Client) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	cutoff := c.now().Add(-age)
+	return c.deleteWhere(ctx, func(msg *sqs.Message) bool {
+		sent, err := SentTime(msg)
+		return err == nil && sent.Before(cutoff)
+	}, 0)
+}
+
+// DeleteMatching deletes messages for which pred returns true and returns the number deleted. Other
+// messages are made visible again immediately. The queue is scanned the same way as in
+// DeleteOlderThan, with the same limitations, but at most maxIterations batches are received so
+// that the scan ends on a busy queue. If maxIterations is 0 the scan is not bounded.
+func (c *Client) DeleteMatching(ctx context.Context, pred func(*sqs.Message) bool, maxIterations int) (int, error) {
+	return c.deleteWhere(ctx, pred, maxIterations)
+}
+
+// deleteWhere scans the queue, deleting messages for which pred returns true and making the rest
+// visible again, until a receive returns only messages that have already been seen or, if
+// maxIterations is not 0, maxIterations batches have been received.
+func (c *Client) deleteWhere(ctx context.Context, pred func(*sqs.Message) bool, maxIterations int) (int, error) {
+	seen := make(map[string]bool)
+	deleted := 0
+	for i := 0; maxIterations == 0 || i < maxIterations; i++ {
+		resp, err := c.receiveNitemsContext(ctx, MaxBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		var matched, rest []*sqs.Message
+		fresh := 0
+		for _, msg := range resp.Messages {
+			id := aws.StringValue(msg.MessageId)
+			if !seen[id] {
+				seen[id] = true
+				fresh++
+				if pred(msg) {
+					matched = append(matched, msg)
+					continue
+				}
+			}
+			rest = append(rest, msg)
+		}
+
+		if len(rest) > 0 {
+			if err := c.changeVisibilityBatch(rest, 0); err != nil {
+				return deleted, err
+			}
+		}
+
+		if len(matched) > 0 {
+			if err := c.DeleteBatch(matched); err != nil {
+				return deleted, err
+			}
+			deleted += len(matched)
+		}
+
+		if fresh == 0 {
+			break
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,96 @@
+package sqs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// processAfterAttribute is the message attribute holding the time set by InsertAt, in milliseconds
+// since the epoch.
+const processAfterAttribute = "process-after"
+
+// maxDelay is the longest delivery delay SQS supports.
+const maxDelay = 15 * time.Minute
+
+// InsertAt inserts a string into the queue that should not be processed before t. SQS can delay a
+// message by at most 15 minutes, so the message is also marked with t and consumers using Consume
+// with HonorProcessAfter re-insert it with a new delay until t has passed. This is an approximation
+// based on polling: the message is delivered at the first receive after t, not exactly at t, and
+// every re-insert is an extra request. FIFO queues do not allow per-message delays, so InsertAt
+// returns ErrFIFOUnsupported for them.
+func (c *Client) InsertAt(input string, t time.Time) error {
+	if c.config.isFIFO() {
+		return ErrFIFOUnsupported
+	}
+
+	return c.sendMessage(&sqs.SendMessageInput{
+		DelaySeconds: aws.Int64(int64(delaySeconds(t.Sub(c.now())))),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			processAfterAttribute: {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)),
+			},
+		},
+		MessageBody: &input,
+		QueueUrl:    c.currentURL(),
+	})
+}
+
+// SetQueueDelay changes the delivery delay applied to every message sent to the queue. It must be
+// between 0 and 900 seconds and only affects messages sent after the change.
+func (c *Client) SetQueueDelay(seconds int) error {
+	if err := validateDelay(seconds); err != nil {
+		return err
+	}
+
+	return c.setAttributes(map[string]string{
+		sqs.QueueAttributeNameDelaySeconds: strconv.Itoa(seconds),
+	})
+}
+
+// validateDelay checks that a delivery delay is within the range SQS allows.
+func validateDelay(seconds int) error {
+	if seconds < 0 || time.Duration(seconds)*time.Second > maxDelay {
+		return fmt.Errorf("sqs: delay of %d seconds must be between 0 and 900", seconds)
+	}
+
+	return nil
+}
+
+// processAfterDelay returns how long until a message set by InsertAt should be processed, or 0 if it
+// is ready or has no process after time.
+func processAfterDelay(msg *sqs.Message, now time.Time) time.Duration {
+	v, ok := msg.MessageAttributes[processAfterAttribute]
+	if !ok {
+		return 0
+	}
+
+	ms, err := strconv.ParseInt(aws.StringValue(v.StringValue), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	wait := time.Unix(0, ms*int64(time.Millisecond)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// delaySeconds converts d to a delivery delay in whole seconds, capped at the SQS maximum.
+func delaySeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	if d > maxDelay {
+		d = maxDelay
+	}
+
+	return int((d + time.Second - 1) / time.Second)
+}
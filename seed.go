@@ -0,0 +1,116 @@
+package sqs
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Seed inserts any number of strings into the queue in batches. It is intended for loading test
+// fixtures, for example into LocalStack, and stops at the first batch that fails.
+func (c *Client) Seed(bodies []string) error {
+	for len(bodies) > 0 {
+		n := nextBatchLen(bodies)
+		results, err := c.InsertBatchResults(bodies[:n])
+		if err != nil {
+			return err
+		}
+
+		for _, r := range results {
+			if r.Err != nil {
+				return r.Err
+			}
+		}
+
+		bodies = bodies[n:]
+	}
+
+	return nil
+}
+
+// SeedN inserts n synthetic messages with the bodies "message-0" to "message-<n-1>".
+func (c *Client) SeedN(n int) error {
+	bodies := make([]string, n)
+	for i := range bodies {
+		bodies[i] = "message-" + strconv.Itoa(i)
+	}
+
+	return c.Seed(bodies)
+}
+
+// InsertFromOptions configures InsertFrom.
+type InsertFromOptions struct {
+	// FailFast stops InsertFrom after the first batch in which any line fails. By default every
+	// line is attempted.
+	FailFast bool
+}
+
+// InsertFromResult reports which lines InsertFrom inserted.
+type InsertFromResult struct {
+	// Inserted is the number of lines inserted.
+	Inserted int
+	// Failed maps the line number, starting from 1, of each line that was not inserted to the
+	// reason, so exactly those lines can be retried. Lines after a FailFast stop are not included.
+	Failed map[int]error
+}
+
+// InsertFrom inserts each line read from r into the queue as a message, in batches. Lines that fail
+// are recorded in the result and, unless opts.FailFast is set, the remaining lines are still
+// inserted. The returned error is the first failure, or the error from reading r. Reading stops at a
+// line longer than MaxMessageSize.
+func (c *Client) InsertFrom(r io.Reader, opts InsertFromOptions) (*InsertFromResult, error) {
+	result := &InsertFromResult{Failed: make(map[int]error)}
+	var firstErr error
+	var pending []string
+	first := 1 // line number of pending[0]
+
+	// flush inserts batches from pending until fewer than all lines are left, returning false to
+	// stop reading.
+	flush := func(all bool) bool {
+		for len(pending) >= MaxBatchSize || all && len(pending) > 0 {
+			n := nextBatchLen(pending)
+			batch, err := c.InsertBatchResults(pending[:n])
+			failed := false
+			for i := 0; i < n; i++ {
+				e := err
+				if e == nil {
+					e = batch[i].Err
+				}
+
+				if e == nil {
+					result.Inserted++
+					continue
+				}
+
+				result.Failed[first+i] = e
+				if firstErr == nil {
+					firstErr = e
+				}
+				failed = true
+			}
+
+			pending, first = pending[n:], first+n
+			if failed && opts.FailFast {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, MaxMessageSize+1)
+	for scanner.Scan() {
+		pending = append(pending, scanner.Text())
+		if !flush(false) {
+			return result, firstErr
+		}
+	}
+
+	flush(true)
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+
+	return result, firstErr
+}
@@ -0,0 +1,161 @@
+// Package sqsprom exports metrics from an sqs.Client to Prometheus. It is a separate package so
+// that the sqs package does not depend on the Prometheus client library.
+package sqsprom
+
+import (
+	"strings"
+	"time"
+
+	"github.com/arowden/sqs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Queue is the part of *sqs.Client that a Collector reads the queue depth from.
+type Queue interface {
+	Stats() (sqs.Stats, error)
+}
+
+// Collector is a prometheus.Collector that exports the depth of a queue and the measurements a
+// Client reports through its metrics hook. Set it as Config.Metrics when creating the Client, and
+// register it with a prometheus.Registerer:
+//
+//	collector := sqsprom.NewCollector("orders")
+//	client, err := sqs.NewClient(sqs.Config{Name: "orders", Region: "us-east-1", Metrics: collector})
+//	collector.Watch(client)
+//	prometheus.MustRegister(collector)
+//
+// The queue depth is read with Stats on every scrape, so it costs one GetQueueAttributes request
+// per scrape.
+type Collector struct {
+	queue Queue
+
+	visible  *prometheus.Desc
+	inFlight *prometheus.Desc
+	delayed  *prometheus.Desc
+
+	counters *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	gauges   map[string]prometheus.Gauge
+	timings  *prometheus.HistogramVec
+}
+
+// errorsSuffix ends the names of the counters a Client uses to count failed requests, such as
+// "receive_errors".
+const errorsSuffix = "_errors"
+
+// NewCollector creates a Collector for the named queue. The name is added to every metric as the
+// queue label, so collectors for several queues can be registered with the same registry.
+func NewCollector(queueName string) *Collector {
+	labels := prometheus.Labels{"queue": queueName}
+	return &Collector{
+		visible: prometheus.NewDesc("sqs_messages_visible",
+			"Approximate number of messages available to be received.", nil, labels),
+		inFlight: prometheus.NewDesc("sqs_messages_in_flight",
+			"Approximate number of messages received but not yet deleted.", nil, labels),
+		delayed: prometheus.NewDesc("sqs_messages_delayed",
+			"Approximate number of messages not yet available because of a delivery delay.", nil, labels),
+		counters: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "sqs_events_total",
+			Help:        "Counters reported by the SQS client, such as messages received and deleted.",
+			ConstLabels: labels,
+		}, []string{"name"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "sqs_operation_errors_total",
+			Help:        "Failed requests made by the SQS client, by operation.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		gauges: map[string]prometheus.Gauge{
+			"breaker_state": prometheus.NewGauge(prometheus.GaugeOpts{
+				Name:        "sqs_breaker_state",
+				Help:        "State of the circuit breaker: 0 closed, 1 open, 2 half open.",
+				ConstLabels: labels,
+			}),
+			"batch_fill": prometheus.NewGauge(prometheus.GaugeOpts{
+				Name:        "sqs_batch_fill_ratio",
+				Help:        "Fraction of the batch size limit used by the latest batch sent by a BufferedProducer.",
+				ConstLabels: labels,
+			}),
+		},
+		timings: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "sqs_operation_duration_seconds",
+			Help:        "Duration of the send, receive and delete requests made by the SQS client.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+}
+
+// Watch sets the queue whose depth is exported. Until it is called only the measurements reported
+// through the metrics hook are exported. It must be called before the Collector is registered.
+func (c *Collector) Watch(queue Queue) {
+	c.queue = queue
+}
+
+// Count implements sqs.Metrics. Counts of failed requests are exported as
+// sqs_operation_errors_total, and all other counts as sqs_events_total.
+func (c *Collector) Count(name string, n int) {
+	if strings.HasSuffix(name, errorsSuffix) {
+		c.errors.WithLabelValues(strings.TrimSuffix(name, errorsSuffix)).Add(float64(n))
+		return
+	}
+
+	c.counters.WithLabelValues(name).Add(float64(n))
+}
+
+// Gauge implements sqs.Metrics. The breaker state is exported as sqs_breaker_state and the fill of
+// the latest producer batch as sqs_batch_fill_ratio; other gauges are ignored.
+func (c *Collector) Gauge(name string, v float64) {
+	if g, ok := c.gauges[name]; ok {
+		g.Set(v)
+	}
+}
+
+// Timing implements sqs.Metrics.
+func (c *Collector) Timing(name string, d time.Duration) {
+	c.timings.WithLabelValues(name).Observe(d.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.visible
+	ch <- c.inFlight
+	ch <- c.delayed
+	c.counters.Describe(ch)
+	c.errors.Describe(ch)
+	for _, g := range c.gauges {
+		g.Describe(ch)
+	}
+	c.timings.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. If the queue depth cannot be read, the depth metrics are
+// reported as invalid and the scrape fails.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	if c.queue != nil {
+		c.collectStats(ch)
+	}
+
+	c.counters.Collect(ch)
+	c.errors.Collect(ch)
+	for _, g := range c.gauges {
+		g.Collect(ch)
+	}
+	c.timings.Collect(ch)
+}
+
+// collectStats sends the queue depth metrics to ch.
+func (c *Collector) collectStats(ch chan<- prometheus.Metric) {
+	depth := []*prometheus.Desc{c.visible, c.inFlight, c.delayed}
+	stats, err := c.queue.Stats()
+	if err != nil {
+		for _, desc := range depth {
+			ch <- prometheus.NewInvalidMetric(desc, err)
+		}
+		return
+	}
+
+	values := []int{stats.Visible, stats.InFlight, stats.Delayed}
+	for i, desc := range depth {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(values[i]))
+	}
+}
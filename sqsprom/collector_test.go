@@ -0,0 +1,81 @@
+package sqsprom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arowden/sqs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeQueue is a Queue with fixed stats.
+type fakeQueue struct {
+	stats sqs.Stats
+	err   error
+}
+
+func (q fakeQueue) Stats() (sqs.Stats, error) {
+	return q.stats, q.err
+}
+
+func TestCollectorDepth(t *testing.T) {
+	c := NewCollector("orders")
+	c.Watch(fakeQueue{stats: sqs.Stats{Visible: 3, InFlight: 1, Delayed: 2}})
+
+	want := `
+# HELP sqs_messages_visible Approximate number of messages available to be received.
+# TYPE sqs_messages_visible gauge
+sqs_messages_visible{queue="orders"} 3
+# HELP sqs_messages_in_flight Approximate number of messages received but not yet deleted.
+# TYPE sqs_messages_in_flight gauge
+sqs_messages_in_flight{queue="orders"} 1
+# HELP sqs_messages_delayed Approximate number of messages not yet available because of a delivery delay.
+# TYPE sqs_messages_delayed gauge
+sqs_messages_delayed{queue="orders"} 2
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"sqs_messages_visible", "sqs_messages_in_flight", "sqs_messages_delayed")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorDepthError(t *testing.T) {
+	c := NewCollector("orders")
+	c.Watch(fakeQueue{err: errors.New("access denied")})
+
+	if err := testutil.CollectAndCompare(c, strings.NewReader("")); err == nil {
+		t.Error("the scrape succeeded, want it to fail when the queue depth cannot be read")
+	}
+}
+
+func TestCollectorMetrics(t *testing.T) {
+	c := NewCollector("orders")
+	c.Count("received", 2)
+	c.Count("received", 3)
+	c.Count("receive_errors", 1)
+	c.Gauge("breaker_state", float64(sqs.BreakerOpen))
+	c.Gauge("batch_fill", 0.5)
+	c.Gauge("unknown", 1)
+
+	want := `
+# HELP sqs_events_total Counters reported by the SQS client, such as messages received and deleted.
+# TYPE sqs_events_total counter
+sqs_events_total{name="received",queue="orders"} 5
+# HELP sqs_operation_errors_total Failed requests made by the SQS client, by operation.
+# TYPE sqs_operation_errors_total counter
+sqs_operation_errors_total{operation="receive",queue="orders"} 1
+# HELP sqs_breaker_state State of the circuit breaker: 0 closed, 1 open, 2 half open.
+# TYPE sqs_breaker_state gauge
+sqs_breaker_state{queue="orders"} 1
+# HELP sqs_batch_fill_ratio Fraction of the batch size limit used by the latest batch sent by a BufferedProducer.
+# TYPE sqs_batch_fill_ratio gauge
+sqs_batch_fill_ratio{queue="orders"} 0.5
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"sqs_events_total", "sqs_operation_errors_total", "sqs_breaker_state", "sqs_batch_fill_ratio")
+	if err != nil {
+		t.Error(err)
+	}
+}
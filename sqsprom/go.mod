@@ -0,0 +1,19 @@
+module github.com/arowden/sqs/sqsprom
+
+go 1.18
+
+require (
+	github.com/arowden/sqs v0.0.0-20261016023850-d8f0d1c7ca31
+	github.com/prometheus/client_golang v0.9.2
+)
+
+require (
+	github.com/aws/aws-sdk-go v1.19.1 // indirect
+	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910 // indirect
+	github.com/prometheus/common v0.0.0-20181126121408-4724e9255275 // indirect
+	github.com/prometheus/procfs v0.0.0-20181204211112-1dc9a6cbc91a // indirect
+)
@@ -0,0 +1,52 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// emptyThrottle enforces a minimum interval between receives after a receive returned nothing.
+type emptyThrottle struct {
+	mu      sync.Mutex
+	emptyAt time.Time
+}
+
+// wait blocks until delay has passed since the last empty receive, given that it is now now, or
+// until ctx is done, in which case it returns ctx's error.
+func (t *emptyThrottle) wait(ctx context.Context, delay time.Duration, now time.Time) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	emptyAt := t.emptyAt
+	t.mu.Unlock()
+
+	if emptyAt.IsZero() {
+		return nil
+	}
+
+	remaining := delay - now.Sub(emptyAt)
+	if remaining <= 0 {
+		return nil
+	}
+
+	if !sleep(ctx, remaining) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// record notes whether the last receive, made at now, was empty.
+func (t *emptyThrottle) record(empty bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if empty {
+		t.emptyAt = now
+	} else {
+		t.emptyAt = time.Time{}
+	}
+}
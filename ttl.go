@@ -0,0 +1,81 @@
+package sqs
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// expiresAtAttribute is the message attribute holding the time set by InsertWithExpiry, in
+// milliseconds since the epoch.
+const expiresAtAttribute = "expires-at"
+
+// expiredMetric counts messages dropped by Consume because they had expired.
+const expiredMetric = "messages_expired"
+
+// InsertWithExpiry inserts a string into the queue that is no longer relevant after t. Consumers
+// using Consume with DropExpired delete the message instead of delivering it if they receive it
+// after t. The expiry is only enforced by those consumers: SQS keeps the message until it is
+// received, or until the queue's retention period ends. It returns ErrFIFOUnsupported for FIFO
+// queues.
+func (c *Client) InsertWithExpiry(input string, t time.Time) error {
+	if c.config.isFIFO() {
+		return ErrFIFOUnsupported
+	}
+
+	return c.sendMessage(&sqs.SendMessageInput{
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			expiresAtAttribute: {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)),
+			},
+		},
+		MessageBody: &input,
+		QueueUrl:    c.currentURL(),
+	})
+}
+
+// isExpired reports whether a message inserted with InsertWithExpiry has passed its expiry time.
+func isExpired(msg *sqs.Message, now time.Time) bool {
+	v, ok := msg.MessageAttributes[expiresAtAttribute]
+	if !ok {
+		return false
+	}
+
+	ms, err := strconv.ParseInt(aws.StringValue(v.StringValue), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return now.After(time.Unix(0, ms*int64(time.Millisecond)))
+}
+
+// staleMetric counts messages deleted by PopFresh because they were older than the maximum age.
+const staleMetric = "messages_stale"
+
+// maxFreshAttempts bounds the number of messages PopFresh receives before giving up.
+const maxFreshAttempts = 10
+
+// PopFresh is like Pop but skips stale backlog: a message sent more than maxAge ago, according to
+// its SentTimestamp, is deleted and the next message is tried instead. It returns the first fresh
+// message, or nil if the queue is empty or no fresh message is found within 10 attempts. Messages
+// without a SentTimestamp are treated as fresh.
+func (c *Client) PopFresh(maxAge time.Duration) (*sqs.Message, error) {
+	for i := 0; i < maxFreshAttempts; i++ {
+		msg, err := c.Pop()
+		if err != nil || msg == nil {
+			return nil, err
+		}
+
+		sent, err := SentTime(msg)
+		if err != nil || c.now().Sub(sent) <= maxAge {
+			return msg, nil
+		}
+
+		c.metrics().Count(staleMetric, 1)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,55 @@
+package sqs
+
+// currentURL returns the queue's URL, which may change if the queue is recreated.
+func (c *Client) currentURL() *string {
+	c.urlMu.RLock()
+	defer c.urlMu.RUnlock()
+
+	url := c.url
+	return &url
+}
+
+// withURL calls fn, which sends a request to the queue at *url. If *url is the queue's own URL and
+// the queue no longer exists, for example because it was deleted and recreated with a new URL, the
+// URL is resolved again, stored in *url and fn is called once more. Requests to other queues, such
+// as the quarantine queue, are not retried: resolving the queue's name again would send them to the
+// queue itself.
+func (c *Client) withURL(url **string, fn func() error) error {
+	c.urlMu.RLock()
+	gen := c.urlGen
+	own := *url != nil && **url == c.url
+	c.urlMu.RUnlock()
+
+	err := fn()
+	if !own || !isQueueNotExist(err) {
+		return err
+	}
+
+	if c.resolveURL(gen) != nil {
+		return err
+	}
+
+	*url = c.currentURL()
+	return fn()
+}
+
+// resolveURL looks up the queue's URL again after a request using the URL from generation gen
+// found the queue did not exist. Concurrent callers that saw the same generation wait for a single
+// lookup rather than each making their own.
+func (c *Client) resolveURL(gen int) error {
+	c.urlMu.Lock()
+	defer c.urlMu.Unlock()
+
+	if c.urlGen != gen {
+		return nil
+	}
+
+	url, err := queueURL(c.config.Name, c.client)
+	if err != nil {
+		return err
+	}
+
+	c.url = url
+	c.urlGen++
+	return nil
+}
@@ -0,0 +1,75 @@
+package sqs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestWithURLRefresh(t *testing.T) {
+	errDenied := errors.New("access denied")
+	tests := []struct {
+		name      string
+		sendFails int   // the number of SendMessage calls that find the queue does not exist
+		lookupErr error // returned by the GetQueueUrl call made to refresh the URL
+		refreshed bool
+		err       error
+	}{
+		{name: "refreshed", sendFails: 1, refreshed: true},
+		{name: "retried once", sendFails: 2, refreshed: true, err: errQueueNotExist},
+		{name: "lookup fails", sendFails: 1, lookupErr: errDenied, err: errQueueNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockAPIService()
+			c := newTestClient(t, testConfig("orders"), mock)
+			url := aws.StringValue(c.currentURL())
+			c.url = url + "-deleted"
+
+			for i := 0; i < tt.sendFails; i++ {
+				mock.FailNext("SendMessage", errQueueNotExist)
+			}
+			if tt.lookupErr != nil {
+				mock.FailNext("GetQueueUrl", tt.lookupErr)
+			}
+
+			if err := c.Insert("body"); err != tt.err {
+				t.Fatalf("Insert() = %v, want %v", err, tt.err)
+			}
+
+			if got := aws.StringValue(c.currentURL()); (got == url) != tt.refreshed {
+				t.Errorf("URL after Insert is %q, refreshed = %v, want %v", got, got == url, tt.refreshed)
+			}
+			if sent := len(mock.Sent()); (sent == 1) != (tt.err == nil) {
+				t.Errorf("%d messages sent", sent)
+			}
+		})
+	}
+}
+
+func TestWithURLDoesNotRefreshQuarantine(t *testing.T) {
+	mock := NewMockAPIService("bad")
+	config := testConfig("orders")
+	config.QuarantineQueue = "orders-quarantine"
+	c := newTestClient(t, config, mock)
+
+	msg, err := c.Peek()
+	if err != nil || msg == nil {
+		t.Fatalf("Peek() = %v, %v", msg, err)
+	}
+
+	mock.FailNext("SendMessage", errQueueNotExist)
+	mock.FailNext("GetQueueUrl", errors.New("GetQueueUrl should not be called"))
+	if err := c.quarantine(msg); err != errQueueNotExist {
+		t.Fatalf("quarantine() = %v, want %v", err, errQueueNotExist)
+	}
+
+	if len(mock.failures["GetQueueUrl"]) != 1 {
+		t.Error("the queue's URL was resolved again after the quarantine queue was not found")
+	}
+	if n := len(mock.Remaining()); n != 1 {
+		t.Errorf("%d messages remain, want the message left in the queue", n)
+	}
+}
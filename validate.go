@@ -0,0 +1,112 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// QuarantineError is returned when a received message fails validation and has been moved to the
+// quarantine queue.
+type QuarantineError struct {
+	// MessageID is the ID the message had in the source queue.
+	MessageID string
+	// Err is the error returned by the validation function.
+	Err error
+}
+
+func (e *QuarantineError) Error() string {
+	return fmt.Sprintf("sqs: message %s quarantined: %v", e.MessageID, e.Err)
+}
+
+// Unwrap returns the validation error.
+func (e *QuarantineError) Unwrap() error {
+	return e.Err
+}
+
+// validate checks a received message against the configured validation function. Invalid messages
+// are moved to the quarantine queue if one is configured, otherwise they are left in the queue and
+// the validation error is returned.
+func (c *Client) validate(msg *sqs.Message) error {
+	if c.config.BodyValidator == nil {
+		return nil
+	}
+
+	verr := c.config.BodyValidator(aws.StringValue(msg.Body))
+	if verr == nil {
+		return nil
+	}
+
+	if c.quarantineURL == "" {
+		return verr
+	}
+
+	if err := c.quarantine(msg); err != nil {
+		return err
+	}
+
+	return &QuarantineError{MessageID: aws.StringValue(msg.MessageId), Err: verr}
+}
+
+// validateBatch removes the messages of msgs that fail validation, dealing with each as validate
+// does, and returns the rest along with the error for the first invalid message.
+func (c *Client) validateBatch(msgs []*sqs.Message) ([]*sqs.Message, error) {
+	if c.config.BodyValidator == nil {
+		return msgs, nil
+	}
+
+	var valid []*sqs.Message
+	var first error
+	for _, msg := range msgs {
+		if err := c.validate(msg); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		valid = append(valid, msg)
+	}
+
+	return valid, first
+}
+
+// deliverValid is validateBatch for receives that deliver the messages to the caller, such as
+// PeekBatch and PopBatch: the valid messages are also retained for Replay.
+func (c *Client) deliverValid(msgs []*sqs.Message) ([]*sqs.Message, error) {
+	valid, invalid := c.validateBatch(msgs)
+	c.retain(valid)
+	return valid, invalid
+}
+
+// quarantine sends a copy of msg to the quarantine queue and then deletes it from this queue.
+func (c *Client) quarantine(msg *sqs.Message) error {
+	if err := c.sendToQuarantine(msg); err != nil {
+		return err
+	}
+
+	return c.Delete(msg)
+}
+
+// sendToQuarantine sends a copy of msg to the quarantine queue, with the body and message
+// attributes it was received with. A FIFO quarantine queue gets the copy in the message's original
+// group, or a group of its own if it came from a standard queue, and must have content-based
+// deduplication enabled since the copy has no deduplication ID.
+func (c *Client) sendToQuarantine(msg *sqs.Message) error {
+	request := &sqs.SendMessageInput{
+		MessageAttributes: msg.MessageAttributes,
+		MessageBody:       msg.Body,
+		QueueUrl:          &c.quarantineURL,
+	}
+	if strings.HasSuffix(c.config.QuarantineQueue, fifoSuffix) {
+		groupID, ok := systemAttribute(msg, sqs.MessageSystemAttributeNameMessageGroupId)
+		if !ok {
+			groupID = aws.StringValue(msg.MessageId)
+		}
+		request.MessageGroupId = aws.String(groupID)
+	}
+
+	return c.send(context.Background(), request)
+}
@@ -0,0 +1,164 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+var errInvalidBody = errors.New("invalid body")
+
+// rejectBad is a BodyValidator that rejects the body "bad".
+func rejectBad(body string) error {
+	if body == "bad" {
+		return errInvalidBody
+	}
+
+	return nil
+}
+
+// validatingClient returns a Client that validates with rejectBad, quarantining invalid messages
+// if quarantine is set, and whose queue holds bodies.
+func validatingClient(t *testing.T, quarantine string, bodies ...string) (*Client, *MockAPIService) {
+	t.Helper()
+
+	mock := NewMockAPIService(bodies...)
+	config := testConfig("orders")
+	config.BodyValidator = rejectBad
+	config.QuarantineQueue = quarantine
+
+	return newTestClient(t, config, mock), mock
+}
+
+func TestPeekValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		quarantine  string
+		quarantined bool
+	}{
+		{name: "left in the queue"},
+		{name: "quarantined", quarantine: "orders-quarantine", quarantined: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mock := validatingClient(t, tt.quarantine, "bad")
+
+			msg, err := c.Peek()
+			if msg != nil {
+				t.Errorf("Peek returned invalid message %q", aws.StringValue(msg.Body))
+			}
+			if !errors.Is(err, errInvalidBody) {
+				t.Fatalf("Peek() error = %v, want %v", err, errInvalidBody)
+			}
+
+			var qerr *QuarantineError
+			if errors.As(err, &qerr) != tt.quarantined {
+				t.Errorf("error is a *QuarantineError = %v, want %v", !tt.quarantined, tt.quarantined)
+			}
+			if sent := len(mock.Sent()); (sent == 1) != tt.quarantined {
+				t.Errorf("%d messages sent, want the copy sent only when quarantined", sent)
+			}
+		})
+	}
+}
+
+func TestPeekBatchValidation(t *testing.T) {
+	c, _ := validatingClient(t, "", "first", "bad", "last")
+
+	msgs, err := c.PeekBatch()
+	if !errors.Is(err, errInvalidBody) {
+		t.Errorf("PeekBatch() error = %v, want %v", err, errInvalidBody)
+	}
+
+	if len(msgs) != 2 || aws.StringValue(msgs[0].Body) != "first" || aws.StringValue(msgs[1].Body) != "last" {
+		t.Errorf("PeekBatch returned %d messages, want the two valid ones", len(msgs))
+	}
+}
+
+func TestReceiveJSONValidation(t *testing.T) {
+	c, _ := validatingClient(t, "", `{"n":1}`, "bad", `{"n":`)
+
+	decoded, err := c.ReceiveJSON(context.Background(), MaxBatchSize, func() interface{} { return new(map[string]int) })
+	if err != nil {
+		t.Fatalf("ReceiveJSON: %v", err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("ReceiveJSON returned %d messages, want 3", len(decoded))
+	}
+
+	if decoded[0].Err != nil || (*decoded[0].Value.(*map[string]int))["n"] != 1 {
+		t.Errorf("valid message: Value = %v, Err = %v", decoded[0].Value, decoded[0].Err)
+	}
+	if !errors.Is(decoded[1].Err, errInvalidBody) {
+		t.Errorf("invalid message: Err = %v, want %v", decoded[1].Err, errInvalidBody)
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(decoded[2].Err, &syntaxErr) {
+		t.Errorf("undecodable message: Err = %v, want a JSON error", decoded[2].Err)
+	}
+}
+
+func TestQuarantineSendsMessageAsReceived(t *testing.T) {
+	attrs := map[string]*sqs.MessageAttributeValue{
+		"trace": {DataType: aws.String("String"), StringValue: aws.String("abc")},
+	}
+	tests := []struct {
+		name       string
+		quarantine string
+		groupID    string // the MessageGroupId system attribute of the received message
+		wantGroup  *string
+	}{
+		{name: "standard queue", quarantine: "orders-quarantine"},
+		{name: "FIFO queue", quarantine: "orders-quarantine.fifo", groupID: "customer-1", wantGroup: aws.String("customer-1")},
+		{name: "FIFO queue from a standard queue", quarantine: "orders-quarantine.fifo", wantGroup: aws.String("message-1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &recordingMock{MockAPIService: NewMockAPIService()}
+			config := testConfig("orders.fifo")
+			config.QuarantineQueue = tt.quarantine
+			config.Compress = true
+			config.HashDeduplicationID = true
+			config.DefaultAttributes = map[string]Attribute{"env": {DataType: "String", Value: "prod"}}
+			c, err := newClient(config, mock)
+			if err != nil {
+				t.Fatalf("newClient: %v", err)
+			}
+
+			msg := &sqs.Message{
+				Body:              aws.String("bad"),
+				MessageAttributes: attrs,
+				MessageId:         aws.String("message-1"),
+			}
+			if tt.groupID != "" {
+				msg.Attributes = map[string]*string{sqs.MessageSystemAttributeNameMessageGroupId: aws.String(tt.groupID)}
+			}
+
+			if err := c.sendToQuarantine(msg); err != nil {
+				t.Fatalf("sendToQuarantine: %v", err)
+			}
+			if len(mock.sent) != 1 {
+				t.Fatalf("%d messages sent, want 1", len(mock.sent))
+			}
+
+			sent := mock.sent[0]
+			if aws.StringValue(sent.MessageBody) != "bad" || !reflect.DeepEqual(sent.MessageAttributes, attrs) {
+				t.Errorf("sent %q with %v, want the message as it was received", aws.StringValue(sent.MessageBody), sent.MessageAttributes)
+			}
+			if sent.MessageDeduplicationId != nil {
+				t.Errorf("sent with deduplication ID %q, want none", aws.StringValue(sent.MessageDeduplicationId))
+			}
+			if !reflect.DeepEqual(sent.MessageGroupId, tt.wantGroup) {
+				t.Errorf("sent with group %v, want %v", aws.StringValue(sent.MessageGroupId), aws.StringValue(tt.wantGroup))
+			}
+		})
+	}
+}
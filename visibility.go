@@ -0,0 +1,125 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MaxVisibilityTimeoutSeconds is the longest visibility timeout SQS allows, 12 hours.
+const MaxVisibilityTimeoutSeconds = 43200
+
+// validateVisibilityTimeout checks that a visibility timeout is within the range SQS allows.
+func validateVisibilityTimeout(seconds int) error {
+	if seconds < 0 || seconds > MaxVisibilityTimeoutSeconds {
+		return fmt.Errorf("sqs: visibility timeout of %d seconds must be between 0 and %d",
+			seconds, MaxVisibilityTimeoutSeconds)
+	}
+
+	return nil
+}
+
+// ExtendVisibilityBatch sets the visibility timeout of up to 10 received messages to seconds from
+// now, giving a batch consumer more time to process them. If AWS rejects some of the messages, for
+// example because their receipt handles have expired, a *BatchError keyed by message ID is returned
+// and the other messages are still extended. ErrBatchTooLarge is returned for more than 10
+// messages.
+func (c *Client) ExtendVisibilityBatch(msgs []*sqs.Message, seconds int) error {
+	return c.changeVisibilityBatch(msgs, seconds)
+}
+
+// ReleaseMessage makes a received message visible in the queue again immediately, by setting its
+// visibility timeout to 0, so that another consumer can process it without waiting for the timeout
+// to expire. Use it when a message cannot be handled right now. The message's receipt handle should
+// not be used afterwards. ErrReceiptHandleExpired is returned, wrapped, if the message's visibility
+// timeout had already expired.
+func (c *Client) ReleaseMessage(msg *sqs.Message) error {
+	if err := validateReceiptHandle(msg); err != nil {
+		return err
+	}
+
+	request := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          c.currentURL(),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(0),
+	}
+
+	err := c.guard(context.Background(), func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			_, err := c.client.ChangeMessageVisibility(request)
+			return err
+		})
+	})
+	if aerr, ok := err.(awserr.Error); ok && isReceiptHandleExpired(aerr.Code(), aerr.Message()) {
+		err = fmt.Errorf("%w: %v", ErrReceiptHandleExpired, err)
+	}
+
+	c.handles.remove(msg)
+	return err
+}
+
+// changeVisibilityBatch sets the visibility timeout of up to 10 received messages.
+func (c *Client) changeVisibilityBatch(msgs []*sqs.Message, seconds int) error {
+	if err := validateVisibilityTimeout(seconds); err != nil {
+		return err
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if len(msgs) > MaxBatchSize {
+		return ErrBatchTooLarge
+	}
+
+	entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                msg.MessageId,
+			ReceiptHandle:     msg.ReceiptHandle,
+			VisibilityTimeout: aws.Int64(int64(seconds)),
+		})
+	}
+
+	request := &sqs.ChangeMessageVisibilityBatchInput{
+		Entries:  entries,
+		QueueUrl: c.currentURL(),
+	}
+
+	changed := c.now()
+	var resp *sqs.ChangeMessageVisibilityBatchOutput
+	err := c.guard(context.Background(), func() error {
+		return c.withURL(&request.QueueUrl, func() error {
+			var err error
+			resp, err = c.client.ChangeMessageVisibilityBatch(request)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []*sqs.BatchResultErrorEntry
+	if resp != nil {
+		failed = resp.Failed
+	}
+
+	failedIDs := make(map[string]bool, len(failed))
+	for _, f := range failed {
+		failedIDs[aws.StringValue(f.Id)] = true
+	}
+
+	var succeeded []*sqs.Message
+	for _, msg := range msgs {
+		if !failedIDs[aws.StringValue(msg.MessageId)] {
+			succeeded = append(succeeded, msg)
+		}
+	}
+	c.handles.add(succeeded, changed, time.Duration(seconds)*time.Second)
+
+	return newBatchError(failed)
+}
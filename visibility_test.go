@@ -0,0 +1,44 @@
+package sqs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVisibilityTimeoutBounds(t *testing.T) {
+	tests := []struct {
+		seconds int
+		valid   bool
+	}{
+		{seconds: 0, valid: true},
+		{seconds: 30, valid: true},
+		{seconds: MaxVisibilityTimeoutSeconds, valid: true},
+		{seconds: 50000},
+		{seconds: -1},
+	}
+
+	for _, tt := range tests {
+		config := testConfig("orders")
+		config.VisibilityTimeoutSeconds = tt.seconds
+		if err := config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate() with %d seconds = %v, want valid %v", tt.seconds, err, tt.valid)
+		}
+
+		c := newTestClient(t, testConfig("orders"), NewMockAPIService())
+		if err := c.ExtendVisibilityBatch(nil, tt.seconds); (err == nil) != tt.valid {
+			t.Errorf("ExtendVisibilityBatch() with %d seconds = %v, want valid %v", tt.seconds, err, tt.valid)
+		}
+	}
+}
+
+func TestConsumeRejectsVisibilityTimeout(t *testing.T) {
+	c := newTestClient(t, testConfig("orders"), NewMockAPIService("body"))
+
+	msgs, errs := c.Consume(context.Background(), ConsumeOptions{VisibilityTimeoutSeconds: 50000})
+	if err := <-errs; err == nil {
+		t.Error("Consume accepted a visibility timeout of 50000 seconds")
+	}
+	if msg, ok := <-msgs; ok {
+		t.Errorf("Consume delivered %q after rejecting its options", *msg.Body)
+	}
+}
@@ -0,0 +1,161 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Message is a message received from the queue. It exposes the message without the types of the
+// AWS SDK, so callers do not need to import it. Every way of receiving messages has a variant that
+// returns Messages: PeekMessage, PopMessage, PeekBatchMessages, PopBatchMessages, ReceiveMessages,
+// ConsumeMessages, ProcessMessages and HandleNextMessage.
+type Message struct {
+	raw *sqs.Message
+}
+
+// Body returns the body of the message.
+func (m *Message) Body() string {
+	return aws.StringValue(m.raw.Body)
+}
+
+// ID returns the ID SQS assigned to the message.
+func (m *Message) ID() string {
+	return aws.StringValue(m.raw.MessageId)
+}
+
+// ReceiptHandle returns the handle used to delete the message or change its visibility.
+func (m *Message) ReceiptHandle() string {
+	return aws.StringValue(m.raw.ReceiptHandle)
+}
+
+// Attributes returns the message attributes of the message.
+func (m *Message) Attributes() map[string]Attribute {
+	attrs := make(map[string]Attribute, len(m.raw.MessageAttributes))
+	for name, v := range m.raw.MessageAttributes {
+		attrs[name] = Attribute{
+			DataType: aws.StringValue(v.DataType),
+			Value:    aws.StringValue(v.StringValue),
+			Binary:   v.BinaryValue,
+		}
+	}
+
+	return attrs
+}
+
+// SentTime returns the time the message was sent to the queue.
+func (m *Message) SentTime() (time.Time, error) {
+	return SentTime(m.raw)
+}
+
+// Raw returns the underlying AWS SDK message, for use with the methods of Client that take one.
+func (m *Message) Raw() *sqs.Message {
+	return m.raw
+}
+
+// PeekMessage is like Peek but returns a *Message.
+func (c *Client) PeekMessage() (*Message, error) {
+	return wrap(c.Peek())
+}
+
+// PopMessage is like Pop but returns a *Message.
+func (c *Client) PopMessage() (*Message, error) {
+	return wrap(c.Pop())
+}
+
+// PeekBatchMessages is like PeekBatch but returns Messages.
+func (c *Client) PeekBatchMessages() ([]*Message, error) {
+	return wrapAll(c.PeekBatch())
+}
+
+// PopBatchMessages is like PopBatch but returns Messages.
+func (c *Client) PopBatchMessages() ([]*Message, error) {
+	return wrapAll(c.PopBatch())
+}
+
+// ReceiveMessages is like Receive but returns the received Messages.
+func (c *Client) ReceiveMessages(n int) ([]*Message, error) {
+	result, err := c.Receive(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapAll(result.Messages, nil)
+}
+
+// ConsumeMessages is like Consume but sends Messages. Both channels must be drained as for Consume.
+func (c *Client) ConsumeMessages(ctx context.Context, opts ConsumeOptions) (<-chan *Message, <-chan error) {
+	msgs, errs := c.Consume(ctx, opts)
+	out := make(chan *Message)
+	go func() {
+		defer close(out)
+		for msg := range msgs {
+			select {
+			case out <- &Message{raw: msg}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// MessageHandler is like Handler but is passed a *Message.
+type MessageHandler func(ctx context.Context, m *Message) error
+
+// ProcessMessages is like Process but passes Messages to h.
+func (c *Client) ProcessMessages(ctx context.Context, opts ProcessOptions, h MessageHandler) {
+	c.Process(ctx, opts, unwrapHandler(h))
+}
+
+// HandleNextMessage is like HandleNext but passes a *Message to h.
+func (c *Client) HandleNextMessage(ctx context.Context, h MessageHandler) (bool, error) {
+	return c.HandleNext(ctx, unwrapHandler(h))
+}
+
+// DeleteMessage is like Delete but takes a *Message.
+func (c *Client) DeleteMessage(m *Message) error {
+	return c.Delete(m.raw)
+}
+
+// DeleteMessages is like DeleteBatch but takes Messages.
+func (c *Client) DeleteMessages(ms []*Message) error {
+	raw := make([]*sqs.Message, len(ms))
+	for i, m := range ms {
+		raw[i] = m.raw
+	}
+
+	return c.DeleteBatch(raw)
+}
+
+// wrap converts the result of Peek or Pop.
+func wrap(msg *sqs.Message, err error) (*Message, error) {
+	if msg == nil {
+		return nil, err
+	}
+
+	return &Message{raw: msg}, err
+}
+
+// wrapAll converts the result of a batch receive such as PeekBatch.
+func wrapAll(msgs []*sqs.Message, err error) ([]*Message, error) {
+	if msgs == nil {
+		return nil, err
+	}
+
+	wrapped := make([]*Message, len(msgs))
+	for i, msg := range msgs {
+		wrapped[i] = &Message{raw: msg}
+	}
+
+	return wrapped, err
+}
+
+// unwrapHandler returns a Handler that passes each message to h as a *Message.
+func unwrapHandler(h MessageHandler) Handler {
+	return func(ctx context.Context, msg *sqs.Message) error {
+		return h(ctx, &Message{raw: msg})
+	}
+}